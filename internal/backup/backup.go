@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 const (
@@ -13,16 +14,58 @@ const (
 	backupDailyDir  = "/backup/daily"
 	backupWeeklyDir = "/backup/weekly"
 	cronFile        = "/etc/cron.d/webpanel-backup"
+	webpanelBin     = "/usr/local/bin/webpanel"
+	backupLog       = "/var/log/webpanel-backup.log"
 )
 
+// maxJitter adalah penundaan acak terlama sebelum backup terjadwal berjalan
+const maxJitter = 6 * time.Hour
+
+// defaultSchedules adalah jadwal bawaan setiap jenis tugas backup
+var defaultSchedules = map[string]string{
+	"daily":        "0 2 * * *",
+	"weekly":       "0 3 * * 0",
+	dbSnapshotType: "0 4 * * *",
+}
+
+// ScheduleOptions sets when a backup job runs. At and Cron cannot be combined;
+// without either the default time of the job type is used.
+type ScheduleOptions struct {
+	At     string        // waktu HH:MM
+	Cron   string        // jadwal cron lima kolom atau singkatan seperti @daily
+	Jitter time.Duration // penundaan acak sebelum backup dimulai
+}
+
+// schedule mengembalikan jadwal cron yang sudah divalidasi untuk jenis tugas
+func (o ScheduleOptions) schedule(jobType string) (string, error) {
+	if o.Jitter < 0 || o.Jitter > maxJitter {
+		return "", fmt.Errorf("jitter harus antara 0 dan %s", maxJitter)
+	}
+	switch {
+	case o.At != "" && o.Cron != "":
+		return "", fmt.Errorf("--at dan --cron tidak dapat digunakan bersamaan")
+	case o.At != "":
+		return atSchedule(o.At, jobType == "weekly")
+	case o.Cron != "":
+		return normalizeCron(o.Cron)
+	default:
+		return defaultSchedules[jobType], nil
+	}
+}
+
 // Enable enables backup for a specific domain
-func Enable(backupType, domain string) {
+func Enable(backupType, domain string, options ScheduleOptions) {
 	fmt.Printf("Enabling %s backup for domain: %s\n", backupType, domain)
 	// Validasi tipe backup
 	if backupType != "daily" && backupType != "weekly" {
 		fmt.Printf("Error: Tipe backup tidak valid: %s (harus daily atau weekly)\n", backupType)
 		return
 	}
+	schedule, err := options.schedule(backupType)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
 
 	// Validasi domain
 	siteDir := filepath.Join(sitesDir, domain)
@@ -31,26 +74,23 @@ func Enable(backupType, domain string) {
 		return
 	}
 
-	// Buat direktori backup jika belum ada
-	var backupDir string
-	if backupType == "daily" {
-		backupDir = filepath.Join(backupDailyDir, domain)
-	} else {
-		backupDir = filepath.Join(backupWeeklyDir, domain)
-	}
-
-	if err := os.MkdirAll(backupDir, 0755); err != nil {
+	// Buat direktori snapshot jika belum ada
+	backupDir := filepath.Join(snapshotDir, domain)
+	if err := os.MkdirAll(backupDir, 0700); err != nil {
 		fmt.Printf("Error: Tidak dapat membuat direktori backup: %s\n", err)
 		return
 	}
 
 	// Tambahkan ke cron
-	if err := addToCron(backupType, domain); err != nil {
+	if err := addToCron(backupType, domain, schedule, options.Jitter); err != nil {
 		fmt.Printf("Error: Tidak dapat menambahkan ke cron: %s\n", err)
 		return
 	}
 
-	fmt.Printf("Backup %s untuk %s berhasil diaktifkan\n", backupType, domain)
+	fmt.Printf("Backup %s untuk %s berhasil diaktifkan (%s)\n", backupType, domain, describeJob(newJob(schedule, options.Jitter)))
+	if _, err := os.Stat(legacyBackupDir(backupType, domain)); err == nil {
+		fmt.Printf("Catatan: Salinan rsync lama di %s tidak lagi diperbarui dan dapat dihapus\n", legacyBackupDir(backupType, domain))
+	}
 }
 
 // Disable disables backup for a specific domain
@@ -72,25 +112,60 @@ func Disable(backupType, domain string) {
 }
 
 // AddDBBackup adds database backup for a specific database
-func AddDBBackup(dbName string) {
+func AddDBBackup(dbName string, options ScheduleOptions) {
 	fmt.Printf("Adding database backup for: %s\n", dbName)
 	// Validasi nama database
 	if !isValidDBName(dbName) {
 		fmt.Printf("Error: Nama database tidak valid: %s\n", dbName)
 		return
 	}
+	schedule, err := options.schedule(dbSnapshotType)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
 
 	// Tambahkan ke cron
-	if err := addDBToCron(dbName); err != nil {
+	if err := addDBToCron(dbName, schedule, options.Jitter); err != nil {
 		fmt.Printf("Error: Tidak dapat menambahkan backup database ke cron: %s\n", err)
 		return
 	}
 
-	fmt.Printf("Backup database untuk %s berhasil diaktifkan\n", dbName)
+	fmt.Printf("Backup database untuk %s berhasil diaktifkan (%s)\n", dbName, describeJob(newJob(schedule, options.Jitter)))
+	if _, err := os.Stat(legacyDumpFile(dbName)); err == nil {
+		fmt.Printf("Catatan: Dump lama di %s tidak lagi diperbarui dan dapat dihapus\n", legacyDumpFile(dbName))
+	}
+}
+
+// Schedules returns the backup types enabled for a domain
+func Schedules(domain string) []string {
+	content, err := ioutil.ReadFile(cronFile)
+	if err != nil {
+		return nil
+	}
+
+	schedules := []string{}
+	for _, backupType := range []string{"daily", "weekly"} {
+		if strings.Contains(string(content), runPattern(backupType, domain)) ||
+			strings.Contains(string(content), legacyPattern(backupType, domain)) {
+			schedules = append(schedules, backupType)
+		}
+	}
+	return schedules
+}
+
+// HasDBBackup reports whether a database backup is scheduled for a database
+func HasDBBackup(dbName string) bool {
+	content, err := ioutil.ReadFile(cronFile)
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(content), dbRunPattern(dbName)) ||
+		strings.Contains(string(content), legacyDumpPattern(dbName))
 }
 
 // addToCron menambahkan tugas backup ke cron
-func addToCron(backupType, domain string) error {
+func addToCron(backupType, domain, schedule string, jitter time.Duration) error {
 	// Baca file cron yang ada
 	var cronContent string
 	if _, err := os.Stat(cronFile); !os.IsNotExist(err) {
@@ -101,22 +176,19 @@ func addToCron(backupType, domain string) error {
 		cronContent = string(content)
 	}
 
-	// Buat perintah backup
-	var cronLine string
-	if backupType == "daily" {
-		cronLine = fmt.Sprintf("0 2 * * * root rsync -a --delete %s/ %s/\n",
-			filepath.Join(sitesDir, domain),
-			filepath.Join(backupDailyDir, domain))
-	} else {
-		cronLine = fmt.Sprintf("0 3 * * 0 root rsync -a --delete %s/ %s/\n",
-			filepath.Join(sitesDir, domain),
-			filepath.Join(backupWeeklyDir, domain))
+	// Ganti tugas yang sudah ada, termasuk rsync lama, untuk domain dan tipe yang sama
+	cronContent = removeLines(cronContent, runPattern(backupType, domain))
+	cronContent = removeLines(cronContent, legacyPattern(backupType, domain))
+	if cronContent != "" && !strings.HasSuffix(cronContent, "\n") {
+		cronContent += "\n"
 	}
 
-	// Periksa apakah sudah ada
-	if strings.Contains(cronContent, cronLine) {
-		return nil // Sudah ada, tidak perlu menambahkan lagi
+	// Buat perintah backup
+	command := runPattern(backupType, domain)
+	if jitter > 0 {
+		command += " --jitter " + formatDuration(jitter)
 	}
+	cronLine := fmt.Sprintf("%s root %s >> %s 2>&1\n", schedule, command, backupLog)
 
 	// Tambahkan baris baru
 	cronContent += cronLine
@@ -138,34 +210,148 @@ func removeFromCron(backupType, domain string) error {
 	}
 	cronContent := string(content)
 
-	// Buat pola untuk mencari baris yang akan dihapus
-	var searchPattern string
-	if backupType == "daily" {
-		searchPattern = fmt.Sprintf("rsync -a --delete %s/ %s/",
-			filepath.Join(sitesDir, domain),
-			filepath.Join(backupDailyDir, domain))
-	} else {
-		searchPattern = fmt.Sprintf("rsync -a --delete %s/ %s/",
-			filepath.Join(sitesDir, domain),
-			filepath.Join(backupWeeklyDir, domain))
+	// Hapus tugas baru maupun tugas rsync lama
+	cronContent = removeLines(cronContent, runPattern(backupType, domain))
+	cronContent = removeLines(cronContent, legacyPattern(backupType, domain))
+
+	// Tulis kembali file cron
+	return ioutil.WriteFile(cronFile, []byte(cronContent), 0644)
+}
+
+// Job is a backup scheduled in the cron file
+type Job struct {
+	Domain   string     `json:"domain,omitempty"`
+	Database string     `json:"database,omitempty"`
+	Type     string     `json:"type"`
+	Schedule string     `json:"schedule"`
+	Jitter   string     `json:"jitter,omitempty"`
+	NextRun  *time.Time `json:"next_run,omitempty"`
+	Legacy   bool       `json:"legacy,omitempty"`
+}
+
+// cronJobs membaca semua tugas backup dari file cron
+func cronJobs() ([]Job, error) {
+	content, err := ioutil.ReadFile(cronFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []Job{}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		// Jadwal lima kolom, pengguna, lalu perintah
+		if len(fields) < 7 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		schedule, command := strings.Join(fields[:5], " "), fields[6:]
+		switch {
+		case len(command) >= 5 && command[0] == webpanelBin && command[1] == "backup" && command[2] == "run":
+			job := Job{Domain: command[3], Type: "daily", Schedule: schedule}
+			for i := 4; i < len(command)-1; i++ {
+				switch command[i] {
+				case "--type":
+					job.Type = command[i+1]
+				case "--jitter":
+					job.Jitter = command[i+1]
+				}
+			}
+			jobs = append(jobs, job)
+		case len(command) >= 5 && command[0] == webpanelBin && command[1] == "backup" && command[2] == "dbbackup" && command[3] == "run":
+			job := Job{Database: command[4], Type: dbSnapshotType, Schedule: schedule}
+			for i := 5; i < len(command)-1; i++ {
+				if command[i] == "--jitter" {
+					job.Jitter = command[i+1]
+				}
+			}
+			jobs = append(jobs, job)
+		case len(command) >= 5 && command[0] == "rsync":
+			// rsync -a --delete <sumber>/ <tujuan>/
+			job := Job{Domain: filepath.Base(command[3]), Type: "daily", Schedule: schedule, Legacy: true}
+			if strings.HasPrefix(command[4], backupWeeklyDir+"/") {
+				job.Type = "weekly"
+			}
+			jobs = append(jobs, job)
+		case len(command) >= 4 && command[0] == "mysqldump":
+			// mysqldump -u root <database> > <file>
+			jobs = append(jobs, Job{Database: command[3], Type: dbSnapshotType, Schedule: schedule, Legacy: true})
+		}
+	}
+
+	for i := range jobs {
+		jobs[i].NextRun = nextRun(jobs[i].Schedule)
+	}
+	return jobs, nil
+}
+
+// newJob membuat tugas dengan jadwal dan jitter untuk ditampilkan
+func newJob(schedule string, jitter time.Duration) Job {
+	job := Job{Schedule: schedule, NextRun: nextRun(schedule)}
+	if jitter > 0 {
+		job.Jitter = formatDuration(jitter)
+	}
+	return job
+}
+
+// formatDuration menampilkan durasi tanpa satuan nol, misalnya 30m bukan 30m0s
+func formatDuration(d time.Duration) string {
+	text := d.String()
+	if strings.HasSuffix(text, "m0s") {
+		text = strings.TrimSuffix(text, "0s")
+	}
+	if strings.HasSuffix(text, "h0m") {
+		text = strings.TrimSuffix(text, "0m")
 	}
+	return text
+}
+
+// nextRun mengembalikan waktu jalan berikutnya dari jadwal cron
+func nextRun(schedule string) *time.Time {
+	cron, err := parseCron(schedule)
+	if err != nil {
+		return nil
+	}
+	next := cron.next(time.Now())
+	if next.IsZero() {
+		return nil
+	}
+	return &next
+}
+
+// runPattern mengembalikan perintah cron yang menjalankan backup domain
+func runPattern(backupType, domain string) string {
+	return fmt.Sprintf("%s backup run %s --type %s", webpanelBin, domain, backupType)
+}
 
-	// Hapus baris yang cocok
-	lines := strings.Split(cronContent, "\n")
+// legacyPattern mengembalikan perintah rsync lama untuk backup domain
+func legacyPattern(backupType, domain string) string {
+	return fmt.Sprintf("rsync -a --delete %s/ %s/", filepath.Join(sitesDir, domain), legacyBackupDir(backupType, domain))
+}
+
+// legacyBackupDir mengembalikan direktori salinan rsync lama
+func legacyBackupDir(backupType, domain string) string {
+	if backupType == "weekly" {
+		return filepath.Join(backupWeeklyDir, domain)
+	}
+	return filepath.Join(backupDailyDir, domain)
+}
+
+// removeLines menghapus baris cron yang mengandung pola
+func removeLines(content, pattern string) string {
+	lines := strings.Split(content, "\n")
 	newLines := []string{}
 	for _, line := range lines {
-		if !strings.Contains(line, searchPattern) {
+		if !strings.Contains(line, pattern) {
 			newLines = append(newLines, line)
 		}
 	}
-	newContent := strings.Join(newLines, "\n")
-
-	// Tulis kembali file cron
-	return ioutil.WriteFile(cronFile, []byte(newContent), 0644)
+	return strings.Join(newLines, "\n")
 }
 
 // addDBToCron menambahkan tugas backup database ke cron
-func addDBToCron(dbName string) error {
+func addDBToCron(dbName, schedule string, jitter time.Duration) error {
 	// Baca file cron yang ada
 	var cronContent string
 	if _, err := os.Stat(cronFile); !os.IsNotExist(err) {
@@ -176,29 +362,55 @@ func addDBToCron(dbName string) error {
 		cronContent = string(content)
 	}
 
-	// Buat perintah backup
-	backupDir := filepath.Join(backupDailyDir, "databases")
-	if err := os.MkdirAll(backupDir, 0755); err != nil {
+	// Buat direktori snapshot database
+	if err := os.MkdirAll(databaseSnapshots(dbName), 0700); err != nil {
 		return err
 	}
 
-	cronLine := fmt.Sprintf("0 4 * * * root mysqldump -u root %s > %s/%s.sql\n",
-		dbName, backupDir, dbName)
-
-	// Periksa apakah sudah ada
-	if strings.Contains(cronContent, cronLine) {
-		return nil // Sudah ada, tidak perlu menambahkan lagi
+	// Ganti jadwal yang sudah ada, termasuk mysqldump lama, untuk database yang sama
+	cronContent = removeLines(cronContent, dbRunPattern(dbName))
+	cronContent = removeLines(cronContent, legacyDumpPattern(dbName))
+	if cronContent != "" && !strings.HasSuffix(cronContent, "\n") {
+		cronContent += "\n"
 	}
 
-	// Tambahkan baris baru
-	cronContent += cronLine
+	// Buat perintah backup
+	command := dbRunPattern(dbName)
+	if jitter > 0 {
+		command += " --jitter " + formatDuration(jitter)
+	}
+	cronContent += fmt.Sprintf("%s root %s >> %s 2>&1\n", schedule, command, backupLog)
 
 	// Tulis kembali file cron
 	return ioutil.WriteFile(cronFile, []byte(cronContent), 0644)
 }
 
-// isValidDBName memeriksa apakah nama database valid
+// dbRunPattern mengembalikan perintah cron yang menjalankan backup database;
+// spasi di akhir mencegah nama database yang diawali nama ini ikut cocok
+func dbRunPattern(dbName string) string {
+	return fmt.Sprintf("%s backup dbbackup run %s ", webpanelBin, dbName)
+}
+
+// legacyDumpPattern mengembalikan perintah mysqldump lama untuk database
+func legacyDumpPattern(dbName string) string {
+	return fmt.Sprintf("mysqldump -u root %s >", dbName)
+}
+
+// legacyDumpFile mengembalikan file dump lama yang ditimpa setiap malam
+func legacyDumpFile(dbName string) string {
+	return filepath.Join(backupDailyDir, "databases", dbName+".sql")
+}
+
+// isValidDBName memeriksa apakah nama database valid; nama ditulis ke file
+// cron yang dijalankan oleh shell sehingga hanya huruf, angka dan _ diizinkan
 func isValidDBName(dbName string) bool {
-	// Implementasi sederhana, bisa ditingkatkan dengan validasi yang lebih baik
-	return len(dbName) > 0 && !strings.Contains(dbName, " ") && !strings.Contains(dbName, "/")
+	for _, char := range dbName {
+		if !((char >= 'a' && char <= 'z') ||
+			(char >= 'A' && char <= 'Z') ||
+			(char >= '0' && char <= '9') ||
+			char == '_') {
+			return false
+		}
+	}
+	return len(dbName) > 0
 }
@@ -0,0 +1,96 @@
+package backup
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// Formats are the supported compression formats of snapshot archives
+var Formats = []string{"zst", "gz"}
+
+// defaultFormat memilih zst jika binary zstd tersedia, selain itu gz
+func defaultFormat() string {
+	if _, err := exec.LookPath("zstd"); err == nil {
+		return "zst"
+	}
+	return "gz"
+}
+
+// commandWriter meneruskan data ke stdin perintah eksternal dan menunggu
+// perintah selesai saat ditutup
+type commandWriter struct {
+	io.WriteCloser
+	cmd *exec.Cmd
+}
+
+func (w *commandWriter) Close() error {
+	if err := w.WriteCloser.Close(); err != nil {
+		w.cmd.Wait()
+		return err
+	}
+	if err := w.cmd.Wait(); err != nil {
+		return fmt.Errorf("%s gagal: %w", w.cmd.Path, err)
+	}
+	return nil
+}
+
+// compressWriter membungkus out dengan kompresi sesuai format; Close harus
+// dipanggil agar semua data tertulis
+func compressWriter(format string, out io.Writer) (io.WriteCloser, error) {
+	switch format {
+	case "gz":
+		return gzip.NewWriter(out), nil
+	case "zst":
+		cmd := exec.Command("zstd", "-q", "-T0", "-c")
+		cmd.Stdout = out
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("tidak dapat menjalankan zstd: %w", err)
+		}
+		return &commandWriter{WriteCloser: stdin, cmd: cmd}, nil
+	default:
+		return nil, fmt.Errorf("format kompresi tidak dikenal: %s (gunakan %s)", format, strings.Join(Formats, " atau "))
+	}
+}
+
+// commandReader membaca keluaran perintah eksternal dan menunggu perintah
+// selesai saat ditutup
+type commandReader struct {
+	io.ReadCloser
+	cmd *exec.Cmd
+}
+
+func (r *commandReader) Close() error {
+	r.ReadCloser.Close()
+	if err := r.cmd.Wait(); err != nil {
+		return fmt.Errorf("%s gagal: %w", r.cmd.Path, err)
+	}
+	return nil
+}
+
+// decompressReader membaca data terkompresi dari in sesuai format
+func decompressReader(format string, in io.Reader) (io.ReadCloser, error) {
+	switch format {
+	case "gz":
+		return gzip.NewReader(in)
+	case "zst":
+		cmd := exec.Command("zstd", "-d", "-q", "-c")
+		cmd.Stdin = in
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("tidak dapat menjalankan zstd: %w", err)
+		}
+		return &commandReader{ReadCloser: stdout, cmd: cmd}, nil
+	default:
+		return nil, fmt.Errorf("format kompresi tidak dikenal: %s", format)
+	}
+}
@@ -0,0 +1,88 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const configFile = "/etc/webpanel/backup.json"
+
+// Retention is how many daily, weekly and monthly snapshots are kept. A
+// policy with every count set to zero keeps all snapshots.
+type Retention struct {
+	KeepDaily   int `json:"keep_daily"`
+	KeepWeekly  int `json:"keep_weekly"`
+	KeepMonthly int `json:"keep_monthly"`
+}
+
+// Config is the backup configuration shared by all domains
+type Config struct {
+	Retention Retention               `json:"retention"`
+	Domains   map[string]DomainConfig `json:"domains,omitempty"`
+}
+
+// DomainConfig overrides the global configuration for one domain
+type DomainConfig struct {
+	Retention *Retention `json:"retention,omitempty"`
+}
+
+// defaultRetention dipakai jika belum ada kebijakan yang diatur
+var defaultRetention = Retention{KeepDaily: 7, KeepWeekly: 4, KeepMonthly: 6}
+
+// policy mengembalikan kebijakan retensi yang berlaku untuk domain
+func (c *Config) policy(domain string) Retention {
+	if domainConfig, ok := c.Domains[domain]; ok && domainConfig.Retention != nil {
+		return *domainConfig.Retention
+	}
+	return c.Retention
+}
+
+// String menampilkan kebijakan retensi
+func (r Retention) String() string {
+	if r.disabled() {
+		return "simpan semua"
+	}
+	return fmt.Sprintf("harian %d, mingguan %d, bulanan %d", r.KeepDaily, r.KeepWeekly, r.KeepMonthly)
+}
+
+// disabled memeriksa apakah kebijakan menyimpan semua snapshot
+func (r Retention) disabled() bool {
+	return r.KeepDaily == 0 && r.KeepWeekly == 0 && r.KeepMonthly == 0
+}
+
+// loadConfig membaca konfigurasi backup; nilai yang tidak diatur memakai bawaan
+func loadConfig() (*Config, error) {
+	config := &Config{Retention: defaultRetention, Domains: map[string]DomainConfig{}}
+	content, err := ioutil.ReadFile(configFile)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tidak dapat membaca konfigurasi backup: %w", err)
+	}
+	if err := json.Unmarshal(content, config); err != nil {
+		return nil, fmt.Errorf("konfigurasi backup %s tidak valid: %w", configFile, err)
+	}
+	if config.Domains == nil {
+		config.Domains = map[string]DomainConfig{}
+	}
+	return config, nil
+}
+
+// saveConfig menulis konfigurasi backup
+func saveConfig(config *Config) error {
+	content, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(configFile), 0755); err != nil {
+		return fmt.Errorf("tidak dapat membuat direktori %s: %w", filepath.Dir(configFile), err)
+	}
+	if err := ioutil.WriteFile(configFile, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("tidak dapat menulis konfigurasi backup: %w", err)
+	}
+	return nil
+}
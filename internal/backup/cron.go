@@ -0,0 +1,216 @@
+package backup
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronMacros adalah singkatan jadwal cron beserta bentuk lima kolomnya
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var dayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// cronField adalah batas dan nama nilai satu kolom jadwal cron
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var cronFields = []cronField{
+	{"menit", 0, 59, nil},
+	{"jam", 0, 23, nil},
+	{"tanggal", 1, 31, nil},
+	{"bulan", 1, 12, monthNames},
+	{"hari", 0, 7, dayNames},
+}
+
+// cronSchedule adalah jadwal cron yang sudah diurai; setiap kolom berupa
+// himpunan bit nilai yang cocok
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// Jika salah satu kolom tanggal atau hari berupa *, keduanya harus cocok;
+	// jika tidak, cukup salah satu seperti pada cron
+	domStar, dowStar bool
+}
+
+// parseCron mengurai jadwal cron lima kolom atau singkatan seperti @daily
+func parseCron(expr string) (*cronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if expanded, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = expanded
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("jadwal cron harus terdiri dari 5 kolom (menit jam tanggal bulan hari): %s", expr)
+	}
+
+	bits := make([]uint64, len(fields))
+	for i, field := range fields {
+		value, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, err
+		}
+		bits[i] = value
+	}
+	// Minggu boleh ditulis 0 atau 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+	return &cronSchedule{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: strings.HasPrefix(fields[2], "*"),
+		dowStar: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// parseCronField mengurai satu kolom berisi daftar nilai, rentang dan langkah
+func parseCronField(field string, spec cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			rangePart = part[:i]
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("langkah tidak valid di kolom %s: %s", spec.name, part)
+			}
+		}
+
+		var low, high int
+		switch {
+		case rangePart == "*":
+			low, high = spec.min, spec.max
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = parseCronValue(bounds[0], spec); err != nil {
+				return 0, err
+			}
+			if high, err = parseCronValue(bounds[1], spec); err != nil {
+				return 0, err
+			}
+		default:
+			// Langkah hanya boleh dipakai pada * atau rentang
+			if rangePart != part {
+				return 0, fmt.Errorf("langkah harus dipakai pada * atau rentang di kolom %s: %s", spec.name, part)
+			}
+			value, err := parseCronValue(rangePart, spec)
+			if err != nil {
+				return 0, err
+			}
+			low, high = value, value
+		}
+		if low > high {
+			return 0, fmt.Errorf("rentang terbalik di kolom %s: %s", spec.name, part)
+		}
+		for value := low; value <= high; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+	return bits, nil
+}
+
+// parseCronValue mengurai angka atau nama bulan dan hari
+func parseCronValue(value string, spec cronField) (int, error) {
+	if number, ok := spec.names[strings.ToLower(value)]; ok {
+		return number, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < spec.min || number > spec.max {
+		return 0, fmt.Errorf("nilai tidak valid di kolom %s: %s (harus %d-%d)", spec.name, value, spec.min, spec.max)
+	}
+	return number, nil
+}
+
+// next mengembalikan waktu jalan berikutnya setelah t, atau waktu nol jika
+// jadwal tidak pernah cocok dalam lima tahun ke depan (misalnya 31 Februari)
+func (s *cronSchedule) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		year, month, day := t.Date()
+		switch {
+		case s.month&(1<<uint(month)) == 0:
+			t = time.Date(year, month+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.dayMatches(t):
+			t = time.Date(year, month, day+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(year, month, day, t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches memeriksa kolom tanggal dan hari dengan aturan cron
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// normalizeCron memvalidasi jadwal cron dan mengembalikan bentuk lima kolomnya
+func normalizeCron(expr string) (string, error) {
+	schedule, err := parseCron(expr)
+	if err != nil {
+		return "", err
+	}
+	if schedule.next(time.Now()).IsZero() {
+		return "", fmt.Errorf("jadwal cron tidak pernah berjalan: %s", expr)
+	}
+	if expanded, ok := cronMacros[strings.ToLower(strings.TrimSpace(expr))]; ok {
+		return expanded, nil
+	}
+	return strings.Join(strings.Fields(expr), " "), nil
+}
+
+// atSchedule mengubah waktu HH:MM menjadi jadwal cron harian, atau mingguan
+// pada hari Minggu
+func atSchedule(at string, weekly bool) (string, error) {
+	parts := strings.Split(at, ":")
+	if len(parts) != 2 {
+		return "", fmt.Errorf("waktu tidak valid: %s (gunakan HH:MM)", at)
+	}
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil || hour < 0 || hour > 23 {
+		return "", fmt.Errorf("jam tidak valid: %s (gunakan HH:MM)", at)
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil || minute < 0 || minute > 59 || len(parts[1]) != 2 {
+		return "", fmt.Errorf("menit tidak valid: %s (gunakan HH:MM)", at)
+	}
+	dow := "*"
+	if weekly {
+		dow = "0"
+	}
+	return fmt.Sprintf("%d %d * * %s", minute, hour, dow), nil
+}
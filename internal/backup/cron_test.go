@@ -0,0 +1,174 @@
+package backup
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	// Kamis, 15 Februari 2024 10:30 UTC
+	from := time.Date(2024, time.February, 15, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		expr string
+		want time.Time
+	}{
+		{"every minute", "* * * * *", time.Date(2024, 2, 15, 10, 31, 0, 0, time.UTC)},
+		{"fixed time later today", "45 10 * * *", time.Date(2024, 2, 15, 10, 45, 0, 0, time.UTC)},
+		{"fixed time tomorrow", "0 3 * * *", time.Date(2024, 2, 16, 3, 0, 0, 0, time.UTC)},
+		{"same minute is skipped", "30 10 * * *", time.Date(2024, 2, 16, 10, 30, 0, 0, time.UTC)},
+		{"list", "0 9,12,18 * * *", time.Date(2024, 2, 15, 12, 0, 0, 0, time.UTC)},
+		{"range", "0 1-5 * * *", time.Date(2024, 2, 16, 1, 0, 0, 0, time.UTC)},
+		{"step", "*/20 * * * *", time.Date(2024, 2, 15, 10, 40, 0, 0, time.UTC)},
+		{"range with step", "0 8-20/6 * * *", time.Date(2024, 2, 15, 14, 0, 0, 0, time.UTC)},
+		{"month name", "0 0 1 mar *", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"month name range", "0 0 1 JUN-AUG *", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"day name", "0 0 * * mon", time.Date(2024, 2, 19, 0, 0, 0, 0, time.UTC)},
+		{"day name range", "0 0 * * mon-fri", time.Date(2024, 2, 16, 0, 0, 0, 0, time.UTC)},
+		{"sunday as 0", "0 0 * * 0", time.Date(2024, 2, 18, 0, 0, 0, 0, time.UTC)},
+		{"sunday as 7", "0 0 * * 7", time.Date(2024, 2, 18, 0, 0, 0, 0, time.UTC)},
+		{"leap day", "0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"31st skips short months", "0 0 31 * *", time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)},
+		{"year rollover", "0 0 1 1 *", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"macro daily", "@daily", time.Date(2024, 2, 16, 0, 0, 0, 0, time.UTC)},
+		{"macro hourly", "@hourly", time.Date(2024, 2, 15, 11, 0, 0, 0, time.UTC)},
+		{"macro weekly", "@weekly", time.Date(2024, 2, 18, 0, 0, 0, 0, time.UTC)},
+		{"macro monthly", "@monthly", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"macro yearly", "@YEARLY", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// Tanggal dan hari sama-sama dibatasi: cukup salah satu yang cocok
+		{"dom or dow", "0 0 20 * fri", time.Date(2024, 2, 16, 0, 0, 0, 0, time.UTC)},
+		{"dom or dow dom first", "0 0 17 * mon", time.Date(2024, 2, 17, 0, 0, 0, 0, time.UTC)},
+		// Salah satu berupa *, keduanya harus cocok
+		{"dom with star dow", "0 0 20 * *", time.Date(2024, 2, 20, 0, 0, 0, 0, time.UTC)},
+		{"dow with star dom", "0 0 * * fri", time.Date(2024, 2, 16, 0, 0, 0, 0, time.UTC)},
+		{"stepped dow counts as star", "0 0 13 * */2", time.Date(2024, 4, 13, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := parseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("parseCron(%q): %v", tt.expr, err)
+			}
+			if got := schedule.next(from); !got.Equal(tt.want) {
+				t.Errorf("next(%q) = %s, want %s", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronNeverRuns(t *testing.T) {
+	from := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for _, expr := range []string{"0 0 31 2 *", "0 0 30 2 *", "0 0 31 4,6,9,11 *"} {
+		schedule, err := parseCron(expr)
+		if err != nil {
+			t.Fatalf("parseCron(%q): %v", expr, err)
+		}
+		if got := schedule.next(from); !got.IsZero() {
+			t.Errorf("next(%q) = %s, want zero time", expr, got)
+		}
+		if _, err := normalizeCron(expr); err == nil {
+			t.Errorf("normalizeCron(%q) succeeded, want error", expr)
+		}
+	}
+
+	// Dengan kolom hari, tanggal yang mustahil tetap berjalan setiap Senin di Februari
+	schedule, err := parseCron("0 0 31 2 mon")
+	if err != nil {
+		t.Fatalf("parseCron: %v", err)
+	}
+	want := time.Date(2024, 2, 5, 0, 0, 0, 0, time.UTC)
+	if got := schedule.next(from); !got.Equal(want) {
+		t.Errorf("next = %s, want %s", got, want)
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{"too few fields", "0 0 * *"},
+		{"too many fields", "0 0 * * * *"},
+		{"unknown macro", "@reboot"},
+		{"minute out of range", "60 * * * *"},
+		{"hour out of range", "0 24 * * *"},
+		{"day zero", "0 0 0 * *"},
+		{"month out of range", "0 0 1 13 *"},
+		{"weekday out of range", "0 0 * * 8"},
+		{"reversed range", "0 5-1 * * *"},
+		{"zero step", "*/0 * * * *"},
+		{"bad step", "*/x * * * *"},
+		{"step on single value", "5/10 * * * *"},
+		{"unknown name", "0 0 * foo *"},
+		{"day name in month field", "0 0 * mon *"},
+		{"empty list item", "0 1,,2 * * *"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseCron(tt.expr); err == nil {
+				t.Errorf("parseCron(%q) succeeded, want error", tt.expr)
+			}
+		})
+	}
+}
+
+func TestNormalizeCron(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"@daily", "0 0 * * *"},
+		{" @Weekly ", "0 0 * * 0"},
+		{"0  3   * * mon-fri", "0 3 * * mon-fri"},
+		{"*/15 * * * *", "*/15 * * * *"},
+	}
+
+	for _, tt := range tests {
+		got, err := normalizeCron(tt.expr)
+		if err != nil {
+			t.Errorf("normalizeCron(%q): %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("normalizeCron(%q) = %q, want %q", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestAtSchedule(t *testing.T) {
+	tests := []struct {
+		at      string
+		weekly  bool
+		want    string
+		wantErr bool
+	}{
+		{at: "02:30", want: "30 2 * * *"},
+		{at: "23:05", weekly: true, want: "5 23 * * 0"},
+		{at: "00:00", want: "0 0 * * *"},
+		{at: "24:00", wantErr: true},
+		{at: "12:60", wantErr: true},
+		{at: "12:5", wantErr: true},
+		{at: "1230", wantErr: true},
+		{at: "ab:cd", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := atSchedule(tt.at, tt.weekly)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("atSchedule(%q) = %q, want error", tt.at, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("atSchedule(%q): %v", tt.at, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("atSchedule(%q) = %q, want %q", tt.at, got, tt.want)
+		}
+	}
+}
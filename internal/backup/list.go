@@ -0,0 +1,262 @@
+package backup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DomainBackups lists the schedules and snapshots of a domain
+type DomainBackups struct {
+	Domain    string         `json:"domain"`
+	Schedules []Job          `json:"schedules"`
+	Snapshots []SnapshotInfo `json:"snapshots"`
+	Size      int64          `json:"size"`
+}
+
+// DatabaseBackups lists the schedules and dump snapshots of a database
+type DatabaseBackups struct {
+	Database  string         `json:"database"`
+	Schedules []Job          `json:"schedules"`
+	Snapshots []SnapshotInfo `json:"snapshots"`
+	Size      int64          `json:"size"`
+}
+
+// SnapshotInfo summarizes one snapshot
+type SnapshotInfo struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	Created   time.Time `json:"created"`
+	Age       float64   `json:"age_seconds"`
+	Size      int64     `json:"size"`
+	Databases []string  `json:"databases"`
+}
+
+// backupList adalah keluaran JSON dari List
+type backupList struct {
+	Domains   []DomainBackups   `json:"domains"`
+	Databases []DatabaseBackups `json:"databases"`
+}
+
+// List displays the backup schedules and snapshots of one domain, or of every
+// domain with backups when domain is empty
+func List(domain string, jsonOutput bool) {
+	if !jsonOutput {
+		if domain == "" {
+			fmt.Println("Listing backups:")
+		} else {
+			fmt.Printf("Listing backups for domain: %s\n", domain)
+		}
+	}
+
+	jobs, err := cronJobs()
+	if err != nil {
+		fmt.Printf("Error: Tidak dapat membaca jadwal backup: %s\n", err)
+		return
+	}
+	domains := []string{domain}
+	if domain == "" {
+		if domains, err = backupDomains(jobs); err != nil {
+			fmt.Printf("Error: %s\n", err)
+			return
+		}
+	}
+
+	result := backupList{Domains: []DomainBackups{}, Databases: []DatabaseBackups{}}
+	for _, name := range domains {
+		backups := DomainBackups{Domain: name, Schedules: []Job{}}
+		for _, job := range jobs {
+			if job.Domain == name {
+				backups.Schedules = append(backups.Schedules, job)
+			}
+		}
+		if backups.Snapshots, backups.Size, err = snapshotInfos(domainSnapshots(name)); err != nil {
+			fmt.Printf("Error: Tidak dapat membaca snapshot %s: %s\n", name, err)
+			return
+		}
+		result.Domains = append(result.Domains, backups)
+	}
+	if domain == "" {
+		databases, err := backupDatabases(jobs)
+		if err != nil {
+			fmt.Printf("Error: %s\n", err)
+			return
+		}
+		for _, name := range databases {
+			backups := DatabaseBackups{Database: name, Schedules: []Job{}}
+			for _, job := range jobs {
+				if job.Database == name {
+					backups.Schedules = append(backups.Schedules, job)
+				}
+			}
+			if backups.Snapshots, backups.Size, err = snapshotInfos(databaseSnapshots(name)); err != nil {
+				fmt.Printf("Error: Tidak dapat membaca snapshot database %s: %s\n", name, err)
+				return
+			}
+			result.Databases = append(result.Databases, backups)
+		}
+	}
+
+	if jsonOutput {
+		printJSON(result)
+		return
+	}
+	printList(result)
+}
+
+// printList menampilkan jadwal dan snapshot setiap domain dan database
+func printList(result backupList) {
+	if len(result.Domains) == 0 && len(result.Databases) == 0 {
+		fmt.Println("Belum ada backup yang dijadwalkan atau dibuat")
+		return
+	}
+
+	for _, backups := range result.Domains {
+		fmt.Printf("\n%s\n", backups.Domain)
+		printBackups(backups.Schedules, backups.Snapshots, backups.Size)
+	}
+	for _, backups := range result.Databases {
+		fmt.Printf("\ndatabase %s\n", backups.Database)
+		printBackups(backups.Schedules, backups.Snapshots, backups.Size)
+	}
+	if len(result.Databases) > 0 {
+		fmt.Println("\nPulihkan dump database dengan 'webpanel backup dbbackup restore <dbname> [--snapshot <id>]'")
+	}
+}
+
+// printBackups menampilkan jadwal dan tabel snapshot
+func printBackups(schedules []Job, snapshots []SnapshotInfo, size int64) {
+	if len(schedules) == 0 {
+		fmt.Println("  Jadwal: -")
+	}
+	for _, job := range schedules {
+		fmt.Printf("  Jadwal %s: %s\n", job.Type, describeJob(job))
+	}
+	if len(snapshots) == 0 {
+		fmt.Println("  Snapshot: -")
+		return
+	}
+
+	fmt.Printf("  Snapshot: %d (total %s)\n", len(snapshots), formatSize(size))
+	rows := [][]string{{"    ID", "TIPE", "UKURAN", "UMUR", "DATABASE"}}
+	// Snapshot terbaru ditampilkan lebih dulu
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		info := snapshots[i]
+		age := formatAge(time.Duration(info.Age * float64(time.Second)))
+		rows = append(rows, []string{"    " + info.ID, info.Type, formatSize(info.Size), age, valueOrDash(strings.Join(info.Databases, ", "))})
+	}
+	printTable(rows)
+}
+
+// snapshotInfos meringkas snapshot di direktori beserta total ukurannya
+func snapshotInfos(dir string) ([]SnapshotInfo, int64, error) {
+	manifests, err := listSnapshots(dir)
+	if err != nil {
+		return nil, 0, err
+	}
+	now := time.Now()
+	infos, total := []SnapshotInfo{}, int64(0)
+	for _, manifest := range manifests {
+		info := SnapshotInfo{
+			ID:        manifest.ID,
+			Type:      manifest.Type,
+			Created:   manifest.Created,
+			Age:       now.Sub(manifest.Created).Seconds(),
+			Size:      manifest.totalSize(),
+			Databases: []string{},
+		}
+		for _, db := range manifest.Databases {
+			info.Databases = append(info.Databases, db.Database)
+		}
+		infos = append(infos, info)
+		total += info.Size
+	}
+	return infos, total, nil
+}
+
+// describeJob menampilkan jadwal tugas beserta waktu jalan berikutnya
+func describeJob(job Job) string {
+	description := job.Schedule
+	if job.NextRun != nil {
+		description += ", berikutnya " + job.NextRun.Format("2006-01-02 15:04")
+	}
+	if job.Jitter != "" {
+		description += " + hingga " + job.Jitter
+	}
+	if job.Legacy && job.Domain != "" {
+		description += " [rsync lama]"
+	} else if job.Legacy {
+		description += " [mysqldump lama]"
+	}
+	return description
+}
+
+// backupDomains mengembalikan domain yang memiliki jadwal atau snapshot
+func backupDomains(jobs []Job) ([]string, error) {
+	domains := []string{}
+	for _, job := range jobs {
+		if job.Domain != "" && !contains(domains, job.Domain) {
+			domains = append(domains, job.Domain)
+		}
+	}
+
+	snapshots, err := snapshotDomains()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range snapshots {
+		if !contains(domains, name) {
+			domains = append(domains, name)
+		}
+	}
+	sort.Strings(domains)
+	return domains, nil
+}
+
+// snapshotDomains mengembalikan domain yang memiliki direktori snapshot
+func snapshotDomains() ([]string, error) {
+	domains := []string{}
+	entries, err := ioutil.ReadDir(snapshotDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("tidak dapat membaca direktori snapshot: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			domains = append(domains, entry.Name())
+		}
+	}
+	return domains, nil
+}
+
+// backupDatabases mengembalikan database yang memiliki jadwal atau snapshot
+func backupDatabases(jobs []Job) ([]string, error) {
+	databases, err := snapshotDatabases()
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range jobs {
+		if job.Database != "" && !contains(databases, job.Database) {
+			databases = append(databases, job.Database)
+		}
+	}
+	sort.Strings(databases)
+	return databases, nil
+}
+
+// snapshotDatabases mengembalikan database yang memiliki direktori snapshot
+func snapshotDatabases() ([]string, error) {
+	databases := []string{}
+	entries, err := ioutil.ReadDir(dbSnapshotDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("tidak dapat membaca direktori snapshot database: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			databases = append(databases, entry.Name())
+		}
+	}
+	return databases, nil
+}
@@ -0,0 +1,515 @@
+package backup
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/doko89/webpanel/internal/database"
+)
+
+// preRestoreType adalah tipe snapshot pengaman yang dibuat sebelum restore
+const preRestoreType = "pre-restore"
+
+// RestoreOptions selects what is restored from a snapshot and where
+type RestoreOptions struct {
+	Snapshot  string // ID snapshot, kosong berarti snapshot terbaru
+	FilesOnly bool
+	DBOnly    bool
+	To        string // direktori staging, kosong berarti dipulihkan di tempat
+	Safety    bool   // buat snapshot keadaan saat ini sebelum menimpa
+}
+
+// Restore restores the files and databases of a snapshot, either in place or
+// into a staging directory given by options.To
+func Restore(domain string, options RestoreOptions) {
+	fmt.Printf("Restoring backup for domain: %s\n", domain)
+	if options.FilesOnly && options.DBOnly {
+		fmt.Println("Error: --files-only dan --db-only tidak dapat digunakan bersamaan")
+		return
+	}
+
+	manifest, dir, err := findSnapshot(domainSnapshots(domain), domain, options.Snapshot)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	restoreFiles := !options.DBOnly && manifest.Files != nil
+	databases := []Archive{}
+	if !options.FilesOnly {
+		databases = manifest.Databases
+	}
+	if !restoreFiles && len(databases) == 0 {
+		fmt.Printf("Error: Snapshot %s tidak berisi data yang dapat dipulihkan\n", manifest.ID)
+		return
+	}
+	fmt.Printf("Snapshot: %s (%s, %s)\n", manifest.ID, manifest.Type, manifest.Created.Format("2006-01-02 15:04:05"))
+
+	// Periksa semua arsip sebelum mengubah apa pun
+	archives := append([]Archive{}, databases...)
+	if restoreFiles {
+		archives = append(archives, *manifest.Files)
+	}
+	for _, archive := range archives {
+		if err := verifyArchive(dir, archive); err != nil {
+			fmt.Printf("Error: Snapshot rusak: %s\n", err)
+			return
+		}
+	}
+
+	if options.To != "" {
+		if err := restoreToStaging(dir, manifest, restoreFiles, databases, options.To); err != nil {
+			fmt.Printf("Error: Restore gagal: %s\n", err)
+			return
+		}
+		fmt.Printf("Snapshot %s berhasil dipulihkan ke %s\n", manifest.ID, options.To)
+		return
+	}
+
+	names := []string{}
+	for _, archive := range databases {
+		names = append(names, archive.Database)
+	}
+	target := "file situs " + domain
+	if !restoreFiles {
+		target = "database " + strings.Join(names, ", ")
+	} else if len(names) > 0 {
+		target += " dan database " + strings.Join(names, ", ")
+	}
+	fmt.Printf("Isi %s akan ditimpa dengan snapshot %s. Lanjutkan? (y/N): ", target, manifest.ID)
+	var response string
+	fmt.Scanln(&response)
+	if strings.ToLower(response) != "y" {
+		fmt.Println("Restore dibatalkan")
+		return
+	}
+
+	if options.Safety {
+		safety, err := createSnapshot(domain, preRestoreType, names, defaultFormat())
+		if err != nil {
+			fmt.Printf("Error: Tidak dapat membuat snapshot pengaman, restore dibatalkan: %s\n", err)
+			return
+		}
+		fmt.Printf("Snapshot pengaman %s dibuat\n", safety.ID)
+	}
+
+	if restoreFiles {
+		if err := restoreSiteFiles(dir, domain, *manifest.Files); err != nil {
+			fmt.Printf("Error: Tidak dapat memulihkan file situs: %s\n", err)
+			return
+		}
+	}
+	for _, archive := range databases {
+		fmt.Printf("Memulihkan database %s...\n", archive.Database)
+		if err := restoreDatabase(dir, archive); err != nil {
+			fmt.Printf("Error: %s\n", err)
+			return
+		}
+	}
+
+	fmt.Printf("Snapshot %s untuk %s berhasil dipulihkan\n", manifest.ID, domain)
+}
+
+// RestoreDatabase imports a dump snapshot of a scheduled database backup,
+// either into the database or as an SQL file into a staging directory
+func RestoreDatabase(dbName string, options RestoreOptions) {
+	fmt.Printf("Restoring database backup for: %s\n", dbName)
+	if !isValidDBName(dbName) {
+		fmt.Printf("Error: Nama database tidak valid: %s\n", dbName)
+		return
+	}
+
+	manifest, dir, err := findSnapshot(databaseSnapshots(dbName), "database "+dbName, options.Snapshot)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	databases := []Archive{}
+	for _, archive := range manifest.Databases {
+		if archive.Database == dbName {
+			databases = append(databases, archive)
+		}
+	}
+	if len(databases) == 0 {
+		fmt.Printf("Error: Snapshot %s tidak berisi dump database %s\n", manifest.ID, dbName)
+		return
+	}
+	fmt.Printf("Snapshot: %s (%s, %s)\n", manifest.ID, manifest.Type, manifest.Created.Format("2006-01-02 15:04:05"))
+
+	// Periksa arsip sebelum mengubah apa pun
+	for _, archive := range databases {
+		if err := verifyArchive(dir, archive); err != nil {
+			fmt.Printf("Error: Snapshot rusak: %s\n", err)
+			return
+		}
+	}
+
+	if options.To != "" {
+		if err := restoreToStaging(dir, manifest, false, databases, options.To); err != nil {
+			fmt.Printf("Error: Restore gagal: %s\n", err)
+			return
+		}
+		fmt.Printf("Snapshot %s berhasil dipulihkan ke %s\n", manifest.ID, options.To)
+		return
+	}
+
+	fmt.Printf("Isi database %s akan ditimpa dengan snapshot %s. Lanjutkan? (y/N): ", dbName, manifest.ID)
+	var response string
+	fmt.Scanln(&response)
+	if strings.ToLower(response) != "y" {
+		fmt.Println("Restore dibatalkan")
+		return
+	}
+
+	if options.Safety {
+		safety, err := createDBSnapshot(dbName, preRestoreType, defaultFormat())
+		if err != nil {
+			fmt.Printf("Error: Tidak dapat membuat snapshot pengaman, restore dibatalkan: %s\n", err)
+			return
+		}
+		fmt.Printf("Snapshot pengaman %s dibuat\n", safety.ID)
+	}
+
+	for _, archive := range databases {
+		fmt.Printf("Memulihkan database %s...\n", archive.Database)
+		if err := restoreDatabase(dir, archive); err != nil {
+			fmt.Printf("Error: %s\n", err)
+			return
+		}
+	}
+
+	fmt.Printf("Snapshot %s untuk database %s berhasil dipulihkan\n", manifest.ID, dbName)
+}
+
+// findSnapshot membaca manifest snapshot dengan ID tertentu di direktori
+// snapshot, atau snapshot terbaru jika ID kosong, beserta direktorinya
+func findSnapshot(parentDir, name, id string) (*Manifest, string, error) {
+	if id == "" {
+		manifests, err := listSnapshots(parentDir)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(manifests) == 0 {
+			return nil, "", fmt.Errorf("belum ada snapshot untuk %s", name)
+		}
+		latest := manifests[len(manifests)-1]
+		return latest, filepath.Join(parentDir, latest.ID), nil
+	}
+
+	if strings.ContainsAny(id, "/\\") || strings.HasPrefix(id, ".") {
+		return nil, "", fmt.Errorf("ID snapshot tidak valid: %s", id)
+	}
+	dir := filepath.Join(parentDir, id)
+	manifest, err := readManifest(dir)
+	if os.IsNotExist(err) {
+		return nil, "", fmt.Errorf("snapshot %s untuk %s tidak ditemukan", id, name)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return manifest, dir, nil
+}
+
+// listSnapshots membaca manifest semua snapshot di direktori, diurutkan dari
+// yang terlama
+func listSnapshots(dir string) ([]*Manifest, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	manifests := []*Manifest{}
+	for _, entry := range entries {
+		// Lewati snapshot yang belum selesai ditulis
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		manifest, err := readManifest(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		manifests = append(manifests, manifest)
+	}
+	sort.Slice(manifests, func(i, j int) bool { return manifests[i].ID < manifests[j].ID })
+	return manifests, nil
+}
+
+// readManifest membaca manifest.json di direktori snapshot
+func readManifest(dir string) (*Manifest, error) {
+	content, err := ioutil.ReadFile(filepath.Join(dir, manifestName))
+	if err != nil {
+		return nil, err
+	}
+	manifest := &Manifest{}
+	if err := json.Unmarshal(content, manifest); err != nil {
+		return nil, fmt.Errorf("manifest %s tidak valid: %w", dir, err)
+	}
+	if manifest.Version > manifestVersion {
+		return nil, fmt.Errorf("versi manifest %s tidak didukung: %d", dir, manifest.Version)
+	}
+	return manifest, nil
+}
+
+// verifyArchive memastikan ukuran dan checksum arsip sesuai dengan manifest
+func verifyArchive(dir string, archive Archive) error {
+	f, err := os.Open(filepath.Join(dir, filepath.Base(archive.Name)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return err
+	}
+	if size != archive.Size {
+		return fmt.Errorf("ukuran %s tidak sesuai: %d, seharusnya %d", archive.Name, size, archive.Size)
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != archive.SHA256 {
+		return fmt.Errorf("checksum %s tidak sesuai", archive.Name)
+	}
+	return nil
+}
+
+// openArchive membuka arsip snapshot dan mendekompresinya
+func openArchive(dir string, archive Archive) (io.ReadCloser, error) {
+	f, err := os.Open(filepath.Join(dir, filepath.Base(archive.Name)))
+	if err != nil {
+		return nil, err
+	}
+	r, err := decompressReader(archive.Format, f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &archiveReader{ReadCloser: r, file: f}, nil
+}
+
+// archiveReader menutup pembaca dekompresi beserta file arsipnya
+type archiveReader struct {
+	io.ReadCloser
+	file *os.File
+}
+
+func (r *archiveReader) Close() error {
+	err := r.ReadCloser.Close()
+	r.file.Close()
+	return err
+}
+
+// restoreToStaging memulihkan file ke <to>/files dan menulis dump database
+// ke <to>/databases tanpa mengubah situs maupun database yang berjalan
+func restoreToStaging(dir string, manifest *Manifest, restoreFiles bool, databases []Archive, to string) error {
+	if entries, err := ioutil.ReadDir(to); err == nil && len(entries) > 0 {
+		return fmt.Errorf("direktori tujuan tidak kosong: %s", to)
+	}
+
+	if restoreFiles {
+		if err := os.MkdirAll(filepath.Join(to, "files"), 0755); err != nil {
+			return err
+		}
+		if err := extractArchive(dir, *manifest.Files, filepath.Join(to, "files")); err != nil {
+			return err
+		}
+	}
+
+	for _, archive := range databases {
+		if !isValidDBName(archive.Database) {
+			return fmt.Errorf("nama database tidak valid di manifest: %s", archive.Database)
+		}
+		if err := os.MkdirAll(filepath.Join(to, "databases"), 0700); err != nil {
+			return err
+		}
+		if err := writeDump(dir, archive, filepath.Join(to, "databases", archive.Database+".sql")); err != nil {
+			return fmt.Errorf("database %s: %w", archive.Database, err)
+		}
+	}
+	return nil
+}
+
+// writeDump menulis dump database yang sudah didekompresi ke file
+func writeDump(dir string, archive Archive, target string) error {
+	r, err := openArchive(dir, archive)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return r.Close()
+}
+
+// restoreDatabase mengimpor dump database dari snapshot
+func restoreDatabase(dir string, archive Archive) error {
+	r, err := openArchive(dir, archive)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err := database.ImportDump(archive.Database, r); err != nil {
+		return err
+	}
+	return r.Close()
+}
+
+// restoreSiteFiles mengekstrak arsip ke direktori sementara di samping
+// direktori situs lalu menukarnya, sehingga situs tidak pernah setengah pulih
+func restoreSiteFiles(dir, domain string, archive Archive) error {
+	siteDir := filepath.Join(sitesDir, domain)
+	staging, err := ioutil.TempDir(sitesDir, "."+domain+".restore-")
+	if err != nil {
+		return fmt.Errorf("tidak dapat membuat direktori sementara: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	// Pertahankan izin dan pemilik direktori situs yang ada
+	if info, err := os.Stat(siteDir); err == nil {
+		if err := os.Chmod(staging, info.Mode().Perm()); err != nil {
+			return err
+		}
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+			if err := os.Chown(staging, int(stat.Uid), int(stat.Gid)); err != nil {
+				return err
+			}
+		}
+	} else if err := os.Chmod(staging, 0755); err != nil {
+		return err
+	}
+
+	if err := extractArchive(dir, archive, staging); err != nil {
+		return err
+	}
+
+	old := filepath.Join(sitesDir, "."+domain+".old-"+time.Now().Format(snapshotIDTime))
+	if _, err := os.Stat(siteDir); err == nil {
+		if err := os.Rename(siteDir, old); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(staging, siteDir); err != nil {
+		// Kembalikan direktori situs lama
+		os.Rename(old, siteDir)
+		return err
+	}
+	if err := os.RemoveAll(old); err != nil {
+		fmt.Printf("Peringatan: Tidak dapat menghapus file situs lama di %s: %s\n", old, err)
+	}
+	return nil
+}
+
+// extractArchive mengekstrak arsip file snapshot ke direktori tujuan
+func extractArchive(dir string, archive Archive, destDir string) error {
+	r, err := openArchive(dir, archive)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	destDir, err = filepath.EvalSymlinks(destDir)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("tidak dapat membaca arsip: %w", err)
+		}
+		if err := extractEntry(tr, header, destDir); err != nil {
+			return err
+		}
+	}
+	return r.Close()
+}
+
+// extractEntry mengekstrak satu entri arsip beserta izin, pemilik dan waktunya
+func extractEntry(tr *tar.Reader, header *tar.Header, destDir string) error {
+	name := path.Clean(header.Name)
+	if name == "." {
+		return nil
+	}
+	target := filepath.Join(destDir, filepath.FromSlash(name))
+	// Tolak entri yang keluar dari direktori tujuan
+	if path.IsAbs(name) || !within(destDir, target) {
+		return fmt.Errorf("path tidak aman di arsip: %s", header.Name)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	// Direktori induk tidak boleh berupa symlink yang menunjuk ke luar
+	parent, err := filepath.EvalSymlinks(filepath.Dir(target))
+	if err != nil {
+		return err
+	}
+	if !within(destDir, parent) {
+		return fmt.Errorf("path tidak aman di arsip: %s", header.Name)
+	}
+
+	mode := header.FileInfo().Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+	switch header.Typeflag {
+	case tar.TypeDir:
+		if err := os.MkdirAll(target, 0700); err != nil {
+			return err
+		}
+	case tar.TypeSymlink:
+		if err := os.Symlink(header.Linkname, target); err != nil {
+			return err
+		}
+		return os.Lchown(target, header.Uid, header.Gid)
+	case tar.TypeReg:
+		f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, tr); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+
+	if err := os.Chown(target, header.Uid, header.Gid); err != nil {
+		return err
+	}
+	if err := os.Chmod(target, mode); err != nil {
+		return err
+	}
+	return os.Chtimes(target, header.ModTime, header.ModTime)
+}
+
+// within memeriksa apakah path berada di dalam direktori
+func within(dir, target string) bool {
+	return target == dir || strings.HasPrefix(target, dir+string(os.PathSeparator))
+}
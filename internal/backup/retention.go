@@ -0,0 +1,291 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// prunedTypes adalah tipe snapshot terjadwal yang boleh dipangkas; snapshot
+// manual dan pengaman sebelum restore hanya dihapus secara manual
+var prunedTypes = []string{"daily", "weekly", dbSnapshotType}
+
+// prunePlan adalah snapshot yang disimpan beserta alasannya dan yang dihapus
+type prunePlan struct {
+	keep    []*Manifest
+	reasons map[string][]string
+	remove  []*Manifest
+}
+
+// pruneTarget adalah direktori snapshot beserta kebijakan retensinya
+type pruneTarget struct {
+	name   string
+	dir    string
+	policy Retention
+}
+
+// ShowRetention displays the global retention policy and the domain overrides
+func ShowRetention() {
+	fmt.Println("Showing backup retention:")
+	config, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	fmt.Printf("Global (juga untuk backup database): %s\n", config.Retention)
+
+	domains := []string{}
+	for domain, domainConfig := range config.Domains {
+		if domainConfig.Retention != nil {
+			domains = append(domains, domain)
+		}
+	}
+	sort.Strings(domains)
+	for _, domain := range domains {
+		fmt.Printf("%s: %s\n", domain, config.Domains[domain].Retention)
+	}
+}
+
+// SetRetention changes the retention policy of a domain, or the global policy
+// when domain is empty. Negative counts keep their current value.
+func SetRetention(domain string, daily, weekly, monthly int) {
+	target := "global"
+	if domain != "" {
+		target = domain
+	}
+	fmt.Printf("Setting backup retention for: %s\n", target)
+	config, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+
+	policy := config.policy(domain)
+	for _, change := range []struct {
+		value  int
+		target *int
+	}{{daily, &policy.KeepDaily}, {weekly, &policy.KeepWeekly}, {monthly, &policy.KeepMonthly}} {
+		if change.value >= 0 {
+			*change.target = change.value
+		}
+	}
+
+	if domain == "" {
+		config.Retention = policy
+	} else {
+		domainConfig := config.Domains[domain]
+		domainConfig.Retention = &policy
+		config.Domains[domain] = domainConfig
+	}
+	if err := saveConfig(config); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+
+	fmt.Printf("Retensi backup %s: %s\n", target, policy)
+	fmt.Println("Catatan: Snapshot lama dipangkas setelah backup berikutnya berhasil, pratinjau dengan 'webpanel backup prune --dry-run'")
+}
+
+// UnsetRetention removes the retention override of a domain so the global
+// policy applies again
+func UnsetRetention(domain string) {
+	fmt.Printf("Removing backup retention override for: %s\n", domain)
+	config, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	domainConfig, ok := config.Domains[domain]
+	if !ok || domainConfig.Retention == nil {
+		fmt.Printf("Error: Domain %s tidak memiliki kebijakan retensi sendiri\n", domain)
+		return
+	}
+
+	domainConfig.Retention = nil
+	config.Domains[domain] = domainConfig
+	if domainConfig == (DomainConfig{}) {
+		delete(config.Domains, domain)
+	}
+	if err := saveConfig(config); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+
+	fmt.Printf("Domain %s kembali memakai retensi global: %s\n", domain, config.Retention)
+}
+
+// Prune removes the snapshots that fall outside the retention policy of one
+// domain, or of every domain when domain is empty. With dryRun nothing is
+// removed.
+func Prune(domain string, dryRun bool) {
+	if dryRun {
+		fmt.Println("Previewing backup pruning:")
+	} else {
+		fmt.Println("Pruning backups:")
+	}
+	config, err := loadConfig()
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	// Snapshot domain yang jadwalnya sudah dihapus juga ikut dipangkas
+	domains := []string{domain}
+	if domain == "" {
+		if domains, err = snapshotDomains(); err != nil {
+			fmt.Printf("Error: %s\n", err)
+			return
+		}
+	}
+
+	// Database memakai kebijakan retensi global
+	targets := []pruneTarget{}
+	for _, name := range domains {
+		targets = append(targets, pruneTarget{name, domainSnapshots(name), config.policy(name)})
+	}
+	if domain == "" {
+		databases, err := snapshotDatabases()
+		if err != nil {
+			fmt.Printf("Error: %s\n", err)
+			return
+		}
+		for _, name := range databases {
+			targets = append(targets, pruneTarget{"database " + name, databaseSnapshots(name), config.Retention})
+		}
+	}
+
+	removed, freed := 0, int64(0)
+	for _, target := range targets {
+		manifests, err := listSnapshots(target.dir)
+		if err != nil {
+			fmt.Printf("Error: Tidak dapat membaca snapshot %s: %s\n", target.name, err)
+			return
+		}
+		if len(manifests) == 0 {
+			continue
+		}
+		plan := planPrune(manifests, target.policy)
+
+		fmt.Printf("\n%s (%s)\n", target.name, target.policy)
+		for _, manifest := range plan.keep {
+			fmt.Printf("  simpan  %s  %-11s  %s\n", manifest.ID, manifest.Type, strings.Join(plan.reasons[manifest.ID], ", "))
+		}
+		for _, manifest := range plan.remove {
+			if !dryRun {
+				if err := removeSnapshot(target.dir, manifest.ID); err != nil {
+					fmt.Printf("  Error: Tidak dapat menghapus snapshot %s: %s\n", manifest.ID, err)
+					continue
+				}
+			}
+			fmt.Printf("  hapus   %s  %-11s  %s\n", manifest.ID, manifest.Type, formatSize(manifest.totalSize()))
+			removed++
+			freed += manifest.totalSize()
+		}
+	}
+
+	if dryRun {
+		fmt.Printf("\n%d snapshot akan dihapus (%s), jalankan tanpa --dry-run untuk menghapus\n", removed, formatSize(freed))
+		return
+	}
+	fmt.Printf("\n%d snapshot dihapus (%s dibebaskan)\n", removed, formatSize(freed))
+}
+
+// pruneAfterRun memangkas snapshot di direktori setelah backup berhasil
+// dengan kebijakan retensi domain, atau kebijakan global jika domain kosong
+func pruneAfterRun(dir, domain string) error {
+	config, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	manifests, err := listSnapshots(dir)
+	if err != nil {
+		return err
+	}
+	plan := planPrune(manifests, config.policy(domain))
+	removed := []string{}
+	for _, manifest := range plan.remove {
+		if err := removeSnapshot(dir, manifest.ID); err != nil {
+			return fmt.Errorf("tidak dapat menghapus snapshot %s: %w", manifest.ID, err)
+		}
+		removed = append(removed, manifest.ID)
+	}
+	if len(removed) > 0 {
+		fmt.Printf("Snapshot lama dihapus sesuai retensi (%s): %s\n", config.policy(domain), strings.Join(removed, ", "))
+	}
+	return nil
+}
+
+// planPrune menentukan snapshot yang disimpan dengan pola kakek-ayah-anak:
+// snapshot terbaru dari setiap hari, minggu dan bulan terakhir sesuai jumlah
+// di kebijakan. Snapshot terjadwal terbaru selalu disimpan.
+func planPrune(manifests []*Manifest, policy Retention) prunePlan {
+	plan := prunePlan{reasons: map[string][]string{}}
+
+	// Urutkan dari yang terbaru
+	scheduled := []*Manifest{}
+	for i := len(manifests) - 1; i >= 0; i-- {
+		manifest := manifests[i]
+		if !contains(prunedTypes, manifest.Type) {
+			plan.reasons[manifest.ID] = []string{"tidak dipangkas"}
+			continue
+		}
+		scheduled = append(scheduled, manifest)
+	}
+
+	if policy.disabled() {
+		for _, manifest := range scheduled {
+			plan.reasons[manifest.ID] = []string{"simpan semua"}
+		}
+	} else if len(scheduled) > 0 {
+		plan.reasons[scheduled[0].ID] = []string{"terbaru"}
+	}
+
+	rules := []struct {
+		reason string
+		count  int
+		bucket func(t time.Time) string
+	}{
+		{"harian", policy.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{"mingguan", policy.KeepWeekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-%02d", year, week)
+		}},
+		{"bulanan", policy.KeepMonthly, func(t time.Time) string { return t.Format("2006-01") }},
+	}
+	for _, rule := range rules {
+		seen := map[string]bool{}
+		for _, manifest := range scheduled {
+			if len(seen) >= rule.count {
+				break
+			}
+			bucket := rule.bucket(manifest.Created.Local())
+			if seen[bucket] {
+				continue
+			}
+			seen[bucket] = true
+			plan.reasons[manifest.ID] = append(plan.reasons[manifest.ID], rule.reason)
+		}
+	}
+
+	for i := len(manifests) - 1; i >= 0; i-- {
+		if _, ok := plan.reasons[manifests[i].ID]; ok {
+			plan.keep = append(plan.keep, manifests[i])
+		} else {
+			plan.remove = append(plan.remove, manifests[i])
+		}
+	}
+	return plan
+}
+
+// removeSnapshot menghapus direktori snapshot; direktori diganti nama lebih
+// dulu agar snapshot yang terhapus sebagian tidak terbaca sebagai snapshot
+func removeSnapshot(parentDir, id string) error {
+	dir := filepath.Join(parentDir, id)
+	trash := filepath.Join(parentDir, "."+id+".deleting")
+	if err := os.Rename(dir, trash); err != nil {
+		return err
+	}
+	return os.RemoveAll(trash)
+}
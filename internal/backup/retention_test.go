@@ -0,0 +1,178 @@
+package backup
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// snapshot membuat manifest dengan waktu pembuatan yang dibaca dari ID
+func snapshot(t *testing.T, id, snapshotType string) *Manifest {
+	t.Helper()
+	created, err := time.ParseInLocation(snapshotIDTime, id, time.Local)
+	if err != nil {
+		t.Fatalf("ID snapshot tidak valid %q: %v", id, err)
+	}
+	return &Manifest{ID: id, Type: snapshotType, Created: created}
+}
+
+func manifestIDs(manifests []*Manifest) []string {
+	ids := []string{}
+	for _, manifest := range manifests {
+		ids = append(ids, manifest.ID)
+	}
+	return ids
+}
+
+func TestPlanPrune(t *testing.T) {
+	type snap struct{ id, typ string }
+
+	tests := []struct {
+		name      string
+		snapshots []snap // dari yang terlama, seperti hasil listSnapshots
+		policy    Retention
+		keep      []string // dari yang terbaru
+		remove    []string
+	}{
+		{
+			name:      "newest is kept",
+			snapshots: []snap{{"20240101-030000", "daily"}, {"20240102-030000", "daily"}, {"20240103-030000", "daily"}},
+			policy:    Retention{KeepDaily: 1},
+			keep:      []string{"20240103-030000"},
+			remove:    []string{"20240102-030000", "20240101-030000"},
+		},
+		{
+			name: "daily keeps the newest of each day",
+			snapshots: []snap{
+				{"20240102-030000", "daily"}, {"20240103-030000", "daily"}, {"20240103-150000", "manual"},
+				{"20240103-160000", "daily"},
+			},
+			policy: Retention{KeepDaily: 2},
+			keep:   []string{"20240103-160000", "20240103-150000", "20240102-030000"},
+			remove: []string{"20240103-030000"},
+		},
+		{
+			// Minggu masih termasuk minggu ISO sebelumnya dan 30 Desember 2024
+			// sudah termasuk minggu pertama 2025
+			name: "weekly follows ISO weeks across the year",
+			snapshots: []snap{
+				{"20241222-030000", "weekly"}, {"20241223-030000", "weekly"}, {"20241229-030000", "weekly"},
+				{"20241230-030000", "weekly"}, {"20250101-030000", "weekly"},
+			},
+			policy: Retention{KeepWeekly: 2},
+			keep:   []string{"20250101-030000", "20241229-030000"},
+			remove: []string{"20241230-030000", "20241223-030000", "20241222-030000"},
+		},
+		{
+			name: "monthly keeps the newest of each month",
+			snapshots: []snap{
+				{"20241130-030000", "daily"}, {"20241201-030000", "daily"}, {"20241231-030000", "daily"},
+				{"20250115-030000", "daily"},
+			},
+			policy: Retention{KeepMonthly: 2},
+			keep:   []string{"20250115-030000", "20241231-030000"},
+			remove: []string{"20241201-030000", "20241130-030000"},
+		},
+		{
+			name: "buckets combine",
+			snapshots: []snap{
+				{"20240115-030000", "daily"}, {"20240131-030000", "daily"}, {"20240201-030000", "daily"},
+				{"20240210-030000", "daily"}, {"20240211-030000", "daily"}, {"20240212-030000", "daily"},
+			},
+			policy: Retention{KeepDaily: 2, KeepWeekly: 2, KeepMonthly: 2},
+			keep:   []string{"20240212-030000", "20240211-030000", "20240131-030000"},
+			remove: []string{"20240210-030000", "20240201-030000", "20240115-030000"},
+		},
+		{
+			name:      "zero counts keep all",
+			snapshots: []snap{{"20240101-030000", "daily"}, {"20240108-030000", "weekly"}, {"20240109-030000", "daily"}},
+			policy:    Retention{},
+			keep:      []string{"20240109-030000", "20240108-030000", "20240101-030000"},
+			remove:    []string{},
+		},
+		{
+			name: "manual and pre-restore are never removed",
+			snapshots: []snap{
+				{"20230101-030000", "manual"}, {"20230601-030000", preRestoreType}, {"20240101-030000", "daily"},
+				{"20240102-030000", "daily"},
+			},
+			policy: Retention{KeepDaily: 1},
+			keep:   []string{"20240102-030000", "20230601-030000", "20230101-030000"},
+			remove: []string{"20240101-030000"},
+		},
+		{
+			name: "newest scheduled is kept behind a manual snapshot",
+			snapshots: []snap{
+				{"20240101-030000", "daily"}, {"20240102-030000", "daily"}, {"20240103-030000", "manual"},
+			},
+			policy: Retention{KeepDaily: 1},
+			keep:   []string{"20240103-030000", "20240102-030000"},
+			remove: []string{"20240101-030000"},
+		},
+		{
+			name: "database dumps are pruned",
+			snapshots: []snap{
+				{"20240101-030000", dbSnapshotType}, {"20240102-030000", dbSnapshotType}, {"20240103-030000", dbSnapshotType},
+			},
+			policy: Retention{KeepDaily: 2},
+			keep:   []string{"20240103-030000", "20240102-030000"},
+			remove: []string{"20240101-030000"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifests := []*Manifest{}
+			for _, s := range tt.snapshots {
+				manifests = append(manifests, snapshot(t, s.id, s.typ))
+			}
+			plan := planPrune(manifests, tt.policy)
+			if got := manifestIDs(plan.keep); !reflect.DeepEqual(got, tt.keep) {
+				t.Errorf("keep = %v, want %v", got, tt.keep)
+			}
+			if got := manifestIDs(plan.remove); !reflect.DeepEqual(got, tt.remove) {
+				t.Errorf("remove = %v, want %v", got, tt.remove)
+			}
+		})
+	}
+}
+
+func TestPlanPruneReasons(t *testing.T) {
+	manifests := []*Manifest{
+		snapshot(t, "20240101-030000", "manual"),
+		snapshot(t, "20240201-030000", "daily"),
+		snapshot(t, "20240202-030000", "daily"),
+	}
+
+	tests := []struct {
+		policy Retention
+		want   map[string]string
+	}{
+		{
+			policy: Retention{KeepDaily: 2, KeepWeekly: 1, KeepMonthly: 1},
+			want: map[string]string{
+				"20240202-030000": "terbaru, harian, mingguan, bulanan",
+				"20240201-030000": "harian",
+				"20240101-030000": "tidak dipangkas",
+			},
+		},
+		{
+			policy: Retention{},
+			want: map[string]string{
+				"20240202-030000": "simpan semua",
+				"20240201-030000": "simpan semua",
+				"20240101-030000": "tidak dipangkas",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		plan := planPrune(manifests, tt.policy)
+		for id, want := range tt.want {
+			if got := strings.Join(plan.reasons[id], ", "); got != want {
+				t.Errorf("planPrune(%s) reasons[%s] = %q, want %q", tt.policy, id, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,443 @@
+package backup
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/doko89/webpanel/internal/database"
+)
+
+const (
+	snapshotDir     = "/backup/snapshots"
+	dbSnapshotDir   = "/backup/databases"
+	dbSnapshotType  = "database"
+	manifestName    = "manifest.json"
+	manifestVersion = 1
+	snapshotIDTime  = "20060102-150405"
+	// maxSnapshotAttempts membatasi akhiran ID agar urutan ID tetap sesuai waktu
+	maxSnapshotAttempts = 9
+)
+
+// Types are the snapshot types; daily and weekly are used by cron jobs
+var Types = []string{"daily", "weekly", "manual"}
+
+// Manifest describes the content of a snapshot
+type Manifest struct {
+	Version   int       `json:"version"`
+	ID        string    `json:"id"`
+	Domain    string    `json:"domain"`
+	Type      string    `json:"type"`
+	Created   time.Time `json:"created"`
+	Source    string    `json:"source"`
+	Files     *Archive  `json:"files,omitempty"`
+	Databases []Archive `json:"databases"`
+}
+
+// dumpTrailer ditulis mysqldump di akhir dump yang selesai dengan lengkap.
+// database.Dump selalu meminta komentar sehingga skip-comments di my.cnf tidak
+// menghilangkan baris ini.
+const dumpTrailer = "-- Dump completed"
+
+// Archive is one compressed file of a snapshot
+type Archive struct {
+	Name     string `json:"name"`
+	Database string `json:"database,omitempty"`
+	Format   string `json:"format"`
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`
+	Entries  int    `json:"entries,omitempty"`
+	// Ukuran dump database sebelum dikompresi
+	RawSize int64 `json:"raw_size,omitempty"`
+}
+
+// Run creates a timestamped snapshot of the files of a site and the given
+// databases. An empty format picks zst when zstd is installed, otherwise gz.
+// A positive jitter delays the start by a random duration up to jitter.
+func Run(domain, snapshotType string, databases []string, format string, jitter time.Duration) {
+	fmt.Printf("Running %s backup for domain: %s\n", snapshotType, domain)
+	if !contains(Types, snapshotType) {
+		fmt.Printf("Error: Tipe backup tidak valid: %s (gunakan daily, weekly atau manual)\n", snapshotType)
+		return
+	}
+	if format == "" {
+		format = defaultFormat()
+	}
+	for _, dbName := range databases {
+		if !isValidDBName(dbName) {
+			fmt.Printf("Error: Nama database tidak valid: %s\n", dbName)
+			return
+		}
+	}
+
+	if err := waitJitter(jitter); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+
+	start := time.Now()
+	manifest, err := createSnapshot(domain, snapshotType, databases, format)
+	if statusErr := recordStatus(domain, snapshotType, manifest, time.Since(start), err); statusErr != nil {
+		fmt.Printf("Peringatan: Tidak dapat mencatat status backup: %s\n", statusErr)
+	}
+	if err != nil {
+		fmt.Printf("Error: Backup gagal: %s\n", err)
+		return
+	}
+
+	size := manifest.totalSize()
+	fmt.Printf("Snapshot %s untuk %s berhasil dibuat (%s, %s)\n", manifest.ID, domain, formatSize(size), time.Since(start).Round(time.Second))
+
+	// Pangkas snapshot lama hanya setelah snapshot baru tersimpan
+	if err := pruneAfterRun(domainSnapshots(domain), domain); err != nil {
+		fmt.Printf("Peringatan: Tidak dapat memangkas snapshot lama: %s\n", err)
+	}
+}
+
+// RunDatabase creates a timestamped snapshot holding a compressed dump of one
+// database. A positive jitter delays the start by a random duration up to jitter.
+func RunDatabase(dbName, format string, jitter time.Duration) {
+	fmt.Printf("Running database backup for: %s\n", dbName)
+	if !isValidDBName(dbName) {
+		fmt.Printf("Error: Nama database tidak valid: %s\n", dbName)
+		return
+	}
+	if format == "" {
+		format = defaultFormat()
+	}
+	if err := waitJitter(jitter); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+
+	start := time.Now()
+	manifest, err := createDBSnapshot(dbName, dbSnapshotType, format)
+	if statusErr := recordStatus(dbName, dbSnapshotType, manifest, time.Since(start), err); statusErr != nil {
+		fmt.Printf("Peringatan: Tidak dapat mencatat status backup: %s\n", statusErr)
+	}
+	if err != nil {
+		fmt.Printf("Error: Backup database gagal: %s\n", err)
+		return
+	}
+	fmt.Printf("Snapshot %s untuk database %s berhasil dibuat (%s)\n", manifest.ID, dbName, formatSize(manifest.totalSize()))
+
+	// Database memakai kebijakan retensi global
+	if err := pruneAfterRun(databaseSnapshots(dbName), ""); err != nil {
+		fmt.Printf("Peringatan: Tidak dapat memangkas snapshot lama: %s\n", err)
+	}
+}
+
+// waitJitter menunggu selama durasi acak hingga jitter agar backup semua
+// situs tidak berjalan bersamaan
+func waitJitter(jitter time.Duration) error {
+	if jitter > maxJitter {
+		return fmt.Errorf("jitter maksimal %s", maxJitter)
+	}
+	if jitter <= 0 {
+		return nil
+	}
+	delay := time.Duration(rand.New(rand.NewSource(time.Now().UnixNano())).Int63n(int64(jitter)))
+	fmt.Printf("Menunggu %s sebelum backup dimulai\n", delay.Round(time.Second))
+	time.Sleep(delay)
+	return nil
+}
+
+// createSnapshot membuat snapshot file situs dan database domain
+func createSnapshot(domain, snapshotType string, databases []string, format string) (*Manifest, error) {
+	siteDir := filepath.Join(sitesDir, domain)
+	if info, err := os.Stat(siteDir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("direktori situs tidak ditemukan: %s", siteDir)
+	}
+
+	manifest := newManifest(snapshotType)
+	manifest.Domain = domain
+	manifest.Source = siteDir
+	err := writeSnapshot(domainSnapshots(domain), manifest, func(tmpDir string) error {
+		files, err := writeFilesArchive(siteDir, filepath.Join(tmpDir, "files.tar."+format), format)
+		if err != nil {
+			return fmt.Errorf("arsip file: %w", err)
+		}
+		manifest.Files = files
+		for _, dbName := range databases {
+			archive, err := dumpDatabase(dbName, filepath.Join(tmpDir, "db-"+dbName+".sql."+format), format)
+			if err != nil {
+				return fmt.Errorf("database %s: %w", dbName, err)
+			}
+			manifest.Databases = append(manifest.Databases, *archive)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// createDBSnapshot membuat snapshot yang hanya berisi dump satu database
+func createDBSnapshot(dbName, snapshotType, format string) (*Manifest, error) {
+	manifest := newManifest(snapshotType)
+	manifest.Source = "mysql:" + dbName
+	err := writeSnapshot(databaseSnapshots(dbName), manifest, func(tmpDir string) error {
+		archive, err := dumpDatabase(dbName, filepath.Join(tmpDir, "db-"+dbName+".sql."+format), format)
+		if err != nil {
+			return err
+		}
+		manifest.Databases = append(manifest.Databases, *archive)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// newManifest membuat manifest snapshot baru dengan ID dari waktu sekarang
+func newManifest(snapshotType string) *Manifest {
+	now := time.Now()
+	return &Manifest{
+		Version:   manifestVersion,
+		ID:        now.Format(snapshotIDTime),
+		Type:      snapshotType,
+		Created:   now,
+		Databases: []Archive{},
+	}
+}
+
+// writeSnapshot menulis isi snapshot ke direktori sementara lalu memindahkannya
+// sekaligus, sehingga snapshot yang gagal tidak pernah terlihat lengkap. ID
+// hanya beresolusi detik; jika snapshot dengan ID yang sama sudah ada, misalnya
+// dari backup yang berjalan bersamaan, ID diberi akhiran -2, -3 dan seterusnya.
+func writeSnapshot(parentDir string, manifest *Manifest, write func(tmpDir string) error) error {
+	if err := os.MkdirAll(parentDir, 0700); err != nil {
+		return fmt.Errorf("tidak dapat membuat direktori backup: %w", err)
+	}
+	// Direktori sementara diawali titik agar tidak terbaca sebagai snapshot
+	tmpDir, err := ioutil.TempDir(parentDir, "."+manifest.ID+".tmp")
+	if err != nil {
+		return fmt.Errorf("tidak dapat membuat direktori sementara: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := write(tmpDir); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpDir, 0700); err != nil {
+		return err
+	}
+
+	baseID := manifest.ID
+	for attempt := 1; attempt <= maxSnapshotAttempts; attempt++ {
+		if attempt > 1 {
+			manifest.ID = fmt.Sprintf("%s-%d", baseID, attempt)
+		}
+		finalDir := filepath.Join(parentDir, manifest.ID)
+		if _, err := os.Lstat(finalDir); err == nil {
+			continue
+		}
+
+		content, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(tmpDir, manifestName), append(content, '\n'), 0600); err != nil {
+			return fmt.Errorf("tidak dapat menulis manifest: %w", err)
+		}
+		// Rename gagal jika direktori tujuan sudah dibuat proses lain setelah diperiksa
+		if err := os.Rename(tmpDir, finalDir); err != nil {
+			if _, statErr := os.Lstat(finalDir); statErr == nil {
+				continue
+			}
+			return fmt.Errorf("tidak dapat menyimpan snapshot: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("snapshot %s sudah ada", baseID)
+}
+
+// domainSnapshots mengembalikan direktori snapshot domain
+func domainSnapshots(domain string) string {
+	return filepath.Join(snapshotDir, domain)
+}
+
+// databaseSnapshots mengembalikan direktori snapshot database
+func databaseSnapshots(dbName string) string {
+	return filepath.Join(dbSnapshotDir, dbName)
+}
+
+// writeFilesArchive mengarsipkan isi direktori situs ke file tar terkompresi
+func writeFilesArchive(srcDir, path, format string) (*Archive, error) {
+	archive := &Archive{Name: filepath.Base(path), Format: format}
+	err := writeCompressed(path, format, archive, func(w io.Writer) error {
+		tw := tar.NewWriter(w)
+		err := filepath.Walk(srcDir, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(srcDir, file)
+			if err != nil || rel == "." {
+				return err
+			}
+			// Socket dan perangkat tidak dapat dipulihkan, lewati
+			if !info.Mode().IsRegular() && !info.IsDir() && info.Mode()&os.ModeSymlink == 0 {
+				return nil
+			}
+
+			link := ""
+			if info.Mode()&os.ModeSymlink != 0 {
+				if link, err = os.Readlink(file); err != nil {
+					return err
+				}
+			}
+			header, err := tar.FileInfoHeader(info, link)
+			if err != nil {
+				return err
+			}
+			header.Name = filepath.ToSlash(rel)
+			if info.IsDir() {
+				header.Name += "/"
+			}
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			archive.Entries++
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = io.Copy(tw, f)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return tw.Close()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// dumpDatabase menyimpan dump database ke file terkompresi; dump yang
+// kosong atau terpotong dianggap gagal
+func dumpDatabase(dbName, path, format string) (*Archive, error) {
+	archive := &Archive{Name: filepath.Base(path), Database: dbName, Format: format}
+	err := writeCompressed(path, format, archive, func(w io.Writer) error {
+		check := &dumpCheck{}
+		if err := database.Dump(dbName, io.MultiWriter(w, check)); err != nil {
+			return err
+		}
+		archive.RawSize = check.size
+		if check.size == 0 {
+			return fmt.Errorf("dump database %s kosong", dbName)
+		}
+		if !strings.Contains(string(check.tail), dumpTrailer) {
+			return fmt.Errorf("dump database %s tidak lengkap", dbName)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// dumpCheck menghitung ukuran dump dan menyimpan bagian akhirnya
+type dumpCheck struct {
+	size int64
+	tail []byte
+}
+
+func (c *dumpCheck) Write(p []byte) (int, error) {
+	c.size += int64(len(p))
+	c.tail = append(c.tail, p...)
+	if len(c.tail) > 256 {
+		c.tail = c.tail[len(c.tail)-256:]
+	}
+	return len(p), nil
+}
+
+// writeCompressed menulis data dari write ke file terkompresi dan mencatat
+// ukuran serta checksum file di archive
+func writeCompressed(path, format string, archive *Archive, write func(w io.Writer) error) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	cw, err := compressWriter(format, io.MultiWriter(f, hash))
+	if err != nil {
+		return err
+	}
+	if err := write(cw); err != nil {
+		cw.Close()
+		return err
+	}
+	if err := cw.Close(); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	archive.Size = info.Size()
+	archive.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return nil
+}
+
+// totalSize menjumlahkan ukuran semua arsip snapshot
+func (m *Manifest) totalSize() int64 {
+	var size int64
+	if m.Files != nil {
+		size += m.Files.Size
+	}
+	for _, db := range m.Databases {
+		size += db.Size
+	}
+	return size
+}
+
+// formatSize menampilkan ukuran dalam satuan yang mudah dibaca
+func formatSize(size int64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	value := float64(size)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d B", size)
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
+
+// contains memeriksa apakah daftar berisi nilai
+func contains(items []string, value string) bool {
+	for _, item := range items {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
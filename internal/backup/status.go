@@ -0,0 +1,248 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const statusDir = "/var/lib/webpanel/backup-status"
+
+// JobStatus is the result of the latest runs of a backup job
+type JobStatus struct {
+	Domain      string     `json:"domain,omitempty"`
+	Database    string     `json:"database,omitempty"`
+	Type        string     `json:"type"`
+	Schedule    string     `json:"schedule,omitempty"`
+	Legacy      bool       `json:"legacy,omitempty"`
+	LastRun     *time.Time `json:"last_run,omitempty"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastFailure *time.Time `json:"last_failure,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	Snapshot    string     `json:"snapshot,omitempty"`
+	Size        int64      `json:"size,omitempty"`
+	Duration    float64    `json:"duration_seconds,omitempty"`
+}
+
+// Status displays the last success and failure of every backup job
+func Status(jsonOutput bool) {
+	if !jsonOutput {
+		fmt.Println("Showing backup status:")
+	}
+	statuses, err := jobStatuses()
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	if jsonOutput {
+		printJSON(statuses)
+		return
+	}
+	if len(statuses) == 0 {
+		fmt.Println("Belum ada backup yang dijadwalkan atau dijalankan")
+		return
+	}
+
+	now := time.Now()
+	rows := [][]string{{"DOMAIN/DATABASE", "TIPE", "JADWAL", "TERAKHIR BERHASIL", "TERAKHIR GAGAL", "SNAPSHOT", "UKURAN"}}
+	failed := []JobStatus{}
+	for _, status := range statuses {
+		success, failure, snapshot, size := "-", "-", "-", "-"
+		if status.LastSuccess != nil {
+			success = formatAge(now.Sub(*status.LastSuccess)) + " lalu"
+			snapshot, size = status.Snapshot, formatSize(status.Size)
+		}
+		if status.LastFailure != nil {
+			failure = formatAge(now.Sub(*status.LastFailure)) + " lalu"
+			if status.LastSuccess == nil || status.LastFailure.After(*status.LastSuccess) {
+				failed = append(failed, status)
+			}
+		}
+		if status.Legacy && status.Database != "" {
+			success = "tidak tercatat (mysqldump lama)"
+		} else if status.Legacy {
+			success = "tidak tercatat (rsync lama)"
+		}
+		rows = append(rows, []string{status.name(), status.Type, valueOrDash(status.Schedule), success, failure, snapshot, size})
+	}
+	printTable(rows)
+
+	for _, status := range failed {
+		fmt.Printf("Peringatan: Backup %s %s terakhir gagal: %s\n", status.Type, status.name(), status.Error)
+	}
+}
+
+// jobStatuses menggabungkan tugas di cron dengan status yang tercatat,
+// termasuk backup manual
+func jobStatuses() ([]JobStatus, error) {
+	jobs, err := cronJobs()
+	if err != nil {
+		return nil, fmt.Errorf("tidak dapat membaca jadwal backup: %w", err)
+	}
+	recorded, err := readStatuses()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []JobStatus{}
+	for _, job := range jobs {
+		status := JobStatus{Domain: job.Domain, Database: job.Database, Type: job.Type}
+		key := statusKey(status.name(), job.Type)
+		if saved, ok := recorded[key]; ok {
+			status = saved
+			delete(recorded, key)
+		}
+		status.Schedule, status.Legacy = job.Schedule, job.Legacy
+		statuses = append(statuses, status)
+	}
+	for _, status := range recorded {
+		statuses = append(statuses, status)
+	}
+	// Domain lebih dulu, lalu database
+	sort.Slice(statuses, func(i, j int) bool {
+		if (statuses[i].Database == "") != (statuses[j].Database == "") {
+			return statuses[i].Database == ""
+		}
+		if statuses[i].name() != statuses[j].name() {
+			return statuses[i].name() < statuses[j].name()
+		}
+		return statuses[i].Type < statuses[j].Type
+	})
+	return statuses, nil
+}
+
+// readStatuses membaca semua status yang tercatat, kuncinya "nama.tipe"
+func readStatuses() (map[string]JobStatus, error) {
+	statuses := map[string]JobStatus{}
+	files, err := ioutil.ReadDir(statusDir)
+	if os.IsNotExist(err) {
+		return statuses, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tidak dapat membaca status backup: %w", err)
+	}
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		content, err := ioutil.ReadFile(filepath.Join(statusDir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		status := JobStatus{}
+		if err := json.Unmarshal(content, &status); err != nil {
+			return nil, fmt.Errorf("status backup %s tidak valid: %w", file.Name(), err)
+		}
+		statuses[statusKey(status.name(), status.Type)] = status
+	}
+	return statuses, nil
+}
+
+// recordStatus mencatat hasil backup domain, atau database untuk tipe
+// database; setiap tugas memiliki file sendiri agar tugas cron yang berjalan
+// bersamaan tidak saling menimpa
+func recordStatus(name, snapshotType string, manifest *Manifest, duration time.Duration, runErr error) error {
+	path := filepath.Join(statusDir, statusKey(name, snapshotType)+".json")
+	status := JobStatus{Domain: name, Type: snapshotType}
+	if snapshotType == dbSnapshotType {
+		status = JobStatus{Database: name, Type: snapshotType}
+	}
+	if content, err := ioutil.ReadFile(path); err == nil {
+		json.Unmarshal(content, &status)
+	}
+
+	now := time.Now()
+	status.LastRun = &now
+	status.Duration = duration.Seconds()
+	if runErr != nil {
+		status.LastFailure = &now
+		status.Error = runErr.Error()
+	} else {
+		status.LastSuccess = &now
+		status.Error = ""
+		status.Snapshot = manifest.ID
+		status.Size = manifest.totalSize()
+	}
+
+	content, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(statusDir, 0755); err != nil {
+		return err
+	}
+	tmp := filepath.Join(statusDir, "."+filepath.Base(path)+".tmp")
+	if err := ioutil.WriteFile(tmp, append(content, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// name mengembalikan domain atau database tugas
+func (s JobStatus) name() string {
+	if s.Database != "" {
+		return s.Database
+	}
+	return s.Domain
+}
+
+// statusKey mengembalikan nama file status untuk tugas backup
+func statusKey(name, snapshotType string) string {
+	return name + "." + snapshotType
+}
+
+// formatAge menampilkan durasi dengan satuan terbesar yang sesuai
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "< 1 menit"
+	case d < time.Hour:
+		return fmt.Sprintf("%d menit", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%d jam", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%d hari", int(d.Hours()/24))
+	}
+}
+
+// printTable mencetak baris sebagai kolom rata kiri; baris pertama adalah judul
+func printTable(rows [][]string) {
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			if len(cell) > widths[i] {
+				widths[i] = len(cell)
+			}
+		}
+	}
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = fmt.Sprintf("%-*s", widths[i], cell)
+		}
+		fmt.Println(strings.TrimRight(strings.Join(cells, "  "), " "))
+	}
+}
+
+// printJSON mencetak nilai sebagai JSON
+func printJSON(value interface{}) {
+	content, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	fmt.Println(string(content))
+}
+
+// valueOrDash mengembalikan "-" untuk nilai kosong
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
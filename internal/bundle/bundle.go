@@ -0,0 +1,310 @@
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/doko89/webpanel/internal/backup"
+	"github.com/doko89/webpanel/internal/database"
+	"github.com/doko89/webpanel/pkg/caddy"
+	"github.com/doko89/webpanel/pkg/caddyfile"
+)
+
+const (
+	sitesDir      = "/apps/sites"
+	siteConfigDir = "/etc/caddy/sites.d"
+	moduleDir     = "/etc/caddy/module.d"
+	certDir       = "/etc/caddy/certs"
+
+	manifestName    = "manifest.json"
+	manifestVersion = 1
+)
+
+var phpModulePattern = regexp.MustCompile(`^php(\d+\.\d+)$`)
+
+// Manifest describes the contents of a site bundle
+type Manifest struct {
+	Version    int       `json:"version"`
+	Domain     string    `json:"domain"`
+	Kind       string    `json:"kind"`
+	Created    time.Time `json:"created"`
+	Modules    []string  `json:"modules"`
+	PHPVersion string    `json:"php_version,omitempty"`
+	Root       string    `json:"root,omitempty"`
+	Databases  []string  `json:"databases"`
+	Backups    []string  `json:"backups"`
+	DBBackups  []string  `json:"db_backups"`
+	HasFiles   bool      `json:"has_files"`
+	HasCerts   bool      `json:"has_certs"`
+}
+
+// Export packages a site, its configuration and linked databases into a bundle
+func Export(domain, output string, databases []string) {
+	fmt.Printf("Exporting site %s to: %s\n", domain, output)
+	siteConfig, ok := caddy.FindSiteConfig(domain)
+	if !ok {
+		fmt.Printf("Error: Domain tidak ditemukan: %s\n", domain)
+		return
+	}
+	kind := siteConfig.Kind
+	config, err := ioutil.ReadFile(siteConfig.Path)
+	if err != nil {
+		fmt.Printf("Error: Tidak dapat membaca file konfigurasi: %s\n", err)
+		return
+	}
+
+	manifest := Manifest{
+		Version:   manifestVersion,
+		Domain:    domain,
+		Kind:      kind,
+		Created:   time.Now(),
+		Modules:   importedModules(string(config)),
+		Databases: databases,
+		Backups:   backup.Schedules(domain),
+		DBBackups: []string{},
+	}
+	for _, name := range manifest.Modules {
+		if matches := phpModulePattern.FindStringSubmatch(name); matches != nil {
+			manifest.PHPVersion = matches[1]
+		}
+	}
+	for _, dbName := range databases {
+		if backup.HasDBBackup(dbName) {
+			manifest.DBBackups = append(manifest.DBBackups, dbName)
+		}
+	}
+	// Situs dapat memakai root di luar /apps/sites, misalnya hasil impor dengan --keep-root
+	siteDir := ""
+	if kind == caddy.KindSite {
+		siteDir = siteRoot(string(config), domain)
+		info, err := os.Stat(siteDir)
+		if err != nil || !info.IsDir() {
+			fmt.Printf("Error: Direktori situs %s tidak ditemukan\n", siteDir)
+			return
+		}
+		manifest.Root = siteDir
+		manifest.HasFiles = true
+	}
+	domainCertDir := filepath.Join(certDir, domain)
+	if _, err := os.Stat(domainCertDir); err == nil {
+		manifest.HasCerts = true
+	}
+
+	if err := writeBundle(output, manifest, config, siteDir, domainCertDir); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+
+	fmt.Printf("Bundle %s berhasil dibuat\n", output)
+	fmt.Printf("Jenis: %s, modul: %s, database: %s\n", kind, listOrDash(manifest.Modules), listOrDash(databases))
+}
+
+// writeBundle menulis manifest, konfigurasi, modul, sertifikat, dump database
+// dan file situs ke arsip. Bundle berisi kunci privat dan dump database
+// sehingga hanya dapat dibaca pemiliknya, dan file yang sudah ada tidak ditimpa.
+func writeBundle(output string, manifest Manifest, config []byte, siteDir, domainCertDir string) (err error) {
+	file, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("tidak dapat membuat bundle: %w", err)
+	}
+	defer file.Close()
+	// Hapus bundle yang tidak lengkap
+	defer func() {
+		if err != nil {
+			os.Remove(output)
+		}
+	}()
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+
+	// Manifest ditulis pertama agar dapat dibaca sebelum isi lainnya
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := addBytes(tw, manifestName, manifestData, 0644); err != nil {
+		return err
+	}
+	if err := addBytes(tw, "config/site.conf", config, 0644); err != nil {
+		return err
+	}
+
+	// Sertakan snippet modul agar server tujuan dapat memasangnya jika belum ada
+	for _, name := range manifest.Modules {
+		if phpModulePattern.MatchString(name) {
+			continue
+		}
+		content, err := ioutil.ReadFile(filepath.Join(moduleDir, name))
+		if err != nil {
+			fmt.Printf("Peringatan: Tidak dapat membaca modul %s: %s\n", name, err)
+			continue
+		}
+		if err := addBytes(tw, "modules/"+name, content, 0644); err != nil {
+			return err
+		}
+	}
+
+	if manifest.HasCerts {
+		if err := addTree(tw, domainCertDir, "certs"); err != nil {
+			return fmt.Errorf("tidak dapat menambahkan sertifikat: %w", err)
+		}
+	}
+
+	for _, dbName := range manifest.Databases {
+		fmt.Printf("Membuat dump database %s...\n", dbName)
+		if err := addDatabase(tw, dbName); err != nil {
+			return err
+		}
+	}
+
+	if manifest.HasFiles {
+		if err := addTree(tw, siteDir, "files"); err != nil {
+			return fmt.Errorf("tidak dapat menambahkan file situs: %w", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// addDatabase menambahkan dump database ke arsip melalui file sementara karena ukuran harus diketahui
+func addDatabase(tw *tar.Writer, dbName string) error {
+	tmp, err := ioutil.TempFile("", "webpanel-dump-*.sql")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if err := database.Dump(dbName, tmp); err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	info, err := tmp.Stat()
+	if err != nil {
+		return err
+	}
+
+	header := &tar.Header{
+		Name:    "databases/" + dbName + ".sql",
+		Mode:    0600,
+		Size:    info.Size(),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, tmp)
+	return err
+}
+
+// addBytes menambahkan satu file dari memori ke arsip
+func addBytes(tw *tar.Writer, name string, data []byte, mode int64) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    mode,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+// addTree menambahkan isi direktori ke arsip di bawah prefix
+func addTree(tw *tar.Writer, root, prefix string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		name := prefix
+		if rel != "." {
+			name = prefix + "/" + filepath.ToSlash(rel)
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = name
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+}
+
+// siteRoot mengembalikan direktori root dari konfigurasi situs, atau
+// direktori bawaan jika tidak ada direktif root
+func siteRoot(config, domain string) string {
+	file, err := caddyfile.Parse(config)
+	if err == nil && file.Site() != nil {
+		if root := file.Site().Find("root"); root != nil && len(root.Tokens) > 1 {
+			args := root.Args()
+			return args[len(args)-1]
+		}
+	}
+	return filepath.Join(sitesDir, domain)
+}
+
+// importedModules mengembalikan nama modul yang diimpor oleh blok situs
+func importedModules(config string) []string {
+	modules := []string{}
+	file, err := caddyfile.Parse(config)
+	if err != nil || file.Site() == nil {
+		return modules
+	}
+	for _, node := range file.Site().FindAll("import") {
+		modules = append(modules, node.Arg(0))
+	}
+	return modules
+}
+
+// listOrDash menggabungkan daftar atau mengembalikan tanda strip jika kosong
+func listOrDash(items []string) string {
+	if len(items) == 0 {
+		return "-"
+	}
+	return strings.Join(items, ", ")
+}
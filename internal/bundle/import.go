@@ -0,0 +1,280 @@
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/doko89/webpanel/internal/backup"
+	"github.com/doko89/webpanel/internal/database"
+	"github.com/doko89/webpanel/internal/module"
+	"github.com/doko89/webpanel/pkg/caddy"
+	"github.com/doko89/webpanel/pkg/caddyfile"
+)
+
+// Import recreates a site from a bundle, optionally under a new domain
+func Import(bundlePath, newDomain string) {
+	fmt.Printf("Importing site bundle: %s\n", bundlePath)
+	file, err := os.Open(bundlePath)
+	if err != nil {
+		fmt.Printf("Error: Tidak dapat membuka bundle: %s\n", err)
+		return
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		fmt.Printf("Error: Bundle tidak valid: %s\n", err)
+		return
+	}
+	tr := tar.NewReader(gz)
+
+	// Manifest selalu menjadi entri pertama
+	header, err := tr.Next()
+	if err != nil || header.Name != manifestName {
+		fmt.Println("Error: Bundle tidak valid: manifest tidak ditemukan")
+		return
+	}
+	var manifest Manifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		fmt.Printf("Error: Manifest tidak valid: %s\n", err)
+		return
+	}
+	if manifest.Version > manifestVersion {
+		fmt.Printf("Error: Versi bundle %d tidak didukung\n", manifest.Version)
+		return
+	}
+
+	domain := manifest.Domain
+	if newDomain != "" {
+		domain = newDomain
+	}
+	if existing, ok := caddy.FindSiteConfig(domain); ok {
+		fmt.Printf("Error: Konfigurasi untuk %s sudah ada di %s\n", domain, existing.Path)
+		return
+	}
+	for _, dbName := range manifest.Databases {
+		exists, err := database.Exists(dbName)
+		if err != nil {
+			fmt.Printf("Error: %s\n", err)
+			return
+		}
+		if exists {
+			fmt.Printf("Error: Database %s sudah ada di server ini\n", dbName)
+			return
+		}
+	}
+	if manifest.PHPVersion != "" && !module.IsAvailable("php"+manifest.PHPVersion) {
+		fmt.Printf("Error: PHP %s belum terinstal, jalankan 'webpanel php install %s' terlebih dahulu\n", manifest.PHPVersion, manifest.PHPVersion)
+		return
+	}
+
+	siteDir := filepath.Join(sitesDir, domain)
+	configPath := filepath.Join(siteConfigDir, caddy.ConfigFileName(domain, manifest.Kind))
+	installedModules := []string{}
+	var newConfig []byte
+
+	// Konfigurasi situs baru dipasang setelah semua isi bundle berhasil
+	// diekstrak; jika gagal, modul yang baru dipasang dihapus kembali
+	rollback := func() {
+		for _, name := range installedModules {
+			os.Remove(filepath.Join(moduleDir, name))
+		}
+		fmt.Printf("Catatan: File situs di %s dan database yang diimpor tidak dihapus\n", siteDir)
+	}
+
+	// Proses setiap entri sesuai urutan penulisan di bundle
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fmt.Printf("Error: Tidak dapat membaca bundle: %s\n", err)
+			rollback()
+			return
+		}
+
+		switch {
+		case header.Name == "config/site.conf":
+			config, err := ioutil.ReadAll(tr)
+			if err != nil {
+				fmt.Printf("Error: Tidak dapat membaca konfigurasi: %s\n", err)
+				rollback()
+				return
+			}
+			rewritten, err := rewriteConfig(string(config), manifest, domain)
+			if err != nil {
+				fmt.Printf("Error: Konfigurasi di bundle tidak valid: %s\n", err)
+				rollback()
+				return
+			}
+			newConfig = []byte(rewritten)
+
+		case strings.HasPrefix(header.Name, "modules/"):
+			name := strings.TrimPrefix(header.Name, "modules/")
+			if module.IsAvailable(name) {
+				continue
+			}
+			if err := extractFile(tr, header, moduleDir, name); err != nil {
+				fmt.Printf("Error: Tidak dapat memasang modul %s: %s\n", name, err)
+				rollback()
+				return
+			}
+			installedModules = append(installedModules, name)
+
+		case strings.HasPrefix(header.Name, "certs/"):
+			rel := strings.TrimPrefix(header.Name, "certs/")
+			if err := extractFile(tr, header, filepath.Join(certDir, domain), rel); err != nil {
+				fmt.Printf("Error: Tidak dapat memulihkan sertifikat: %s\n", err)
+				rollback()
+				return
+			}
+
+		case strings.HasPrefix(header.Name, "databases/"):
+			dbName := strings.TrimSuffix(strings.TrimPrefix(header.Name, "databases/"), ".sql")
+			fmt.Printf("Mengimpor database %s...\n", dbName)
+			if err := database.ImportDump(dbName, tr); err != nil {
+				fmt.Printf("Error: %s\n", err)
+				rollback()
+				return
+			}
+
+		case header.Name == "files" || strings.HasPrefix(header.Name, "files/"):
+			rel := strings.TrimPrefix(strings.TrimPrefix(header.Name, "files"), "/")
+			if err := extractFile(tr, header, siteDir, rel); err != nil {
+				fmt.Printf("Error: Tidak dapat mengekstrak file situs: %s\n", err)
+				rollback()
+				return
+			}
+		}
+	}
+
+	if manifest.HasCerts && domain != manifest.Domain {
+		fmt.Printf("Peringatan: Sertifikat dari %s mungkin tidak berlaku untuk %s\n", manifest.Domain, domain)
+	}
+
+	if newConfig == nil {
+		fmt.Println("Error: Bundle tidak valid: konfigurasi situs tidak ditemukan")
+		rollback()
+		return
+	}
+	// Pasang dan validasi konfigurasi, batalkan jika tidak valid
+	if err := caddy.ReplaceConfig(configPath, newConfig); err != nil {
+		fmt.Printf("Error: Konfigurasi hasil impor tidak valid: %s\n", err)
+		rollback()
+		return
+	}
+	if err := caddy.Reload(); err != nil {
+		fmt.Printf("Peringatan: Tidak dapat memuat ulang Caddy: %s\n", err)
+	}
+
+	// Pulihkan jadwal backup
+	for _, backupType := range manifest.Backups {
+		backup.Enable(backupType, domain, backup.ScheduleOptions{})
+	}
+	for _, dbName := range manifest.DBBackups {
+		backup.AddDBBackup(dbName, backup.ScheduleOptions{})
+	}
+
+	fmt.Printf("Situs %s berhasil diimpor\n", domain)
+	if len(manifest.Databases) > 0 {
+		fmt.Println("Catatan: Pengguna database tidak disertakan dalam bundle, buat dengan 'webpanel db create'")
+	}
+}
+
+// rewriteConfig mengganti domain lama dengan domain baru di alamat situs dan
+// path direktori. Root di luar /apps/sites diarahkan ke direktori situs
+// tempat file bundle diekstrak.
+func rewriteConfig(config string, manifest Manifest, newDomain string) (string, error) {
+	oldDomain := manifest.Domain
+	file, err := caddyfile.Parse(config)
+	if err != nil {
+		return "", err
+	}
+	site := file.Site()
+	if site == nil {
+		return "", fmt.Errorf("blok situs tidak ditemukan")
+	}
+
+	if manifest.Root != "" && manifest.Root != filepath.Join(sitesDir, oldDomain) {
+		if root := site.Find("root"); root != nil && len(root.Tokens) > 1 {
+			root.Tokens[len(root.Tokens)-1] = caddyfile.Quote(filepath.Join(sitesDir, newDomain))
+			fmt.Printf("Catatan: Root %s diganti dengan %s\n", manifest.Root, filepath.Join(sitesDir, newDomain))
+		}
+	}
+	if oldDomain == newDomain {
+		return file.String(), nil
+	}
+
+	// Alamat situs, misalnya "example.com"
+	for i, token := range site.Tokens {
+		if token == oldDomain {
+			site.Tokens[i] = newDomain
+		}
+	}
+	replacer := strings.NewReplacer(
+		filepath.Join(sitesDir, oldDomain), filepath.Join(sitesDir, newDomain),
+		filepath.Join(certDir, oldDomain), filepath.Join(certDir, newDomain),
+	)
+	rewriteTokens(site.Block, replacer)
+	return file.String(), nil
+}
+
+// rewriteTokens menerapkan penggantian pada semua token di dalam blok secara rekursif
+func rewriteTokens(nodes []*caddyfile.Node, replacer *strings.Replacer) {
+	for _, node := range nodes {
+		for i, token := range node.Tokens {
+			node.Tokens[i] = replacer.Replace(token)
+		}
+		rewriteTokens(node.Block, replacer)
+	}
+}
+
+// extractFile mengekstrak satu entri arsip ke dalam direktori tujuan
+func extractFile(tr *tar.Reader, header *tar.Header, destDir, rel string) error {
+	target := filepath.Join(destDir, filepath.FromSlash(rel))
+	// Tolak entri yang keluar dari direktori tujuan
+	if target != destDir && !strings.HasPrefix(target, destDir+string(os.PathSeparator)) {
+		return fmt.Errorf("path tidak aman di bundle: %s", header.Name)
+	}
+
+	mode := os.FileMode(header.Mode).Perm()
+	switch header.Typeflag {
+	case tar.TypeDir:
+		return os.MkdirAll(target, mode|0700)
+	case tar.TypeSymlink:
+		// Symlink hanya boleh menunjuk ke dalam direktori situs
+		if filepath.IsAbs(header.Linkname) || strings.Contains(header.Linkname, "..") {
+			return fmt.Errorf("symlink tidak aman di bundle: %s -> %s", header.Name, header.Linkname)
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		os.Remove(target)
+		return os.Symlink(header.Linkname, target)
+	case tar.TypeReg:
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(f, tr); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		return os.Chtimes(target, header.ModTime, header.ModTime)
+	}
+	return nil
+}
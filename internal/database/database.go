@@ -3,6 +3,7 @@ package database
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
@@ -94,6 +95,56 @@ func Delete(dbName string) {
 	fmt.Printf("Database %s dan penggunanya berhasil dihapus\n", dbName)
 }
 
+// Exists reports whether a database exists
+func Exists(dbName string) (bool, error) {
+	if !isValidName(dbName) {
+		return false, fmt.Errorf("nama database tidak valid: %s", dbName)
+	}
+	cmd := exec.Command("mysql", "-N", "-e", fmt.Sprintf("SHOW DATABASES LIKE '%s';", dbName))
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return false, fmt.Errorf("tidak dapat memeriksa database: %v - %s", err, string(output))
+	}
+	return strings.TrimSpace(string(output)) == dbName, nil
+}
+
+// Dump writes an SQL dump of a database to w
+func Dump(dbName string, w io.Writer) error {
+	if !isValidName(dbName) {
+		return fmt.Errorf("nama database tidak valid: %s", dbName)
+	}
+	var stderr strings.Builder
+	// --comments mengalahkan skip-comments di my.cnf, baris "-- Dump completed"
+	// di akhir dump dipakai untuk memeriksa bahwa dump lengkap
+	cmd := exec.Command("mysqldump", "--single-transaction", "--routines", "--triggers", "--comments", dbName)
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("tidak dapat membuat dump database %s: %v - %s", dbName, err, stderr.String())
+	}
+	return nil
+}
+
+// ImportDump creates a database if needed and loads an SQL dump from r into it
+func ImportDump(dbName string, r io.Reader) error {
+	if !isValidName(dbName) {
+		return fmt.Errorf("nama database tidak valid: %s", dbName)
+	}
+	createDBCmd := exec.Command("mysql", "-e", fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`;", dbName))
+	if output, err := createDBCmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("tidak dapat membuat database: %v - %s", err, string(output))
+	}
+
+	var stderr strings.Builder
+	cmd := exec.Command("mysql", dbName)
+	cmd.Stdin = r
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("tidak dapat mengimpor dump database %s: %v - %s", dbName, err, stderr.String())
+	}
+	return nil
+}
+
 // isValidName memeriksa apakah nama database atau pengguna valid
 func isValidName(name string) bool {
 	// Implementasi sederhana, bisa ditingkatkan dengan validasi regex yang lebih baik
@@ -0,0 +1,249 @@
+package importer
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// apacheIgnored adalah direktif Apache yang tidak memerlukan padanan di Caddy
+var apacheIgnored = map[string]bool{
+	"servername":              true,
+	"serveralias":             true,
+	"documentroot":            true,
+	"serveradmin":             true,
+	"errorlog":                true,
+	"customlog":               true,
+	"loglevel":                true,
+	"directoryindex":          true,
+	"options":                 true,
+	"require":                 true,
+	"order":                   true,
+	"allow":                   true,
+	"proxypassreverse":        true,
+	"proxypreservehost":       true,
+	"proxyrequests":           true,
+	"rewriteengine":           true,
+	"rewritecond":             true,
+	"sslengine":               true,
+	"sslcertificatekeyfile":   true,
+	"sslcertificatechainfile": true,
+}
+
+// parseApache mengurai VirtualHost dari konfigurasi Apache
+func parseApache(name, content string) ([]*vhost, error) {
+	hosts := []*vhost{}
+	var host *vhost
+	sections := []string{}
+
+	lines := joinApacheLines(content)
+	for _, l := range lines {
+		line := strings.TrimSpace(l.text)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Tangani pembukaan dan penutupan section seperti <VirtualHost> dan <Directory>
+		if strings.HasPrefix(line, "</") {
+			section := strings.ToLower(strings.Trim(line, "</> "))
+			if len(sections) == 0 || sections[len(sections)-1] != section {
+				return nil, fmt.Errorf("baris %d: penutup %s tidak sesuai", l.number, line)
+			}
+			sections = sections[:len(sections)-1]
+			if section == "virtualhost" && host != nil {
+				if len(host.domains) > 0 {
+					hosts = append(hosts, host)
+				}
+				host = nil
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "<") {
+			fields := strings.Fields(strings.Trim(line, "<>"))
+			section := strings.ToLower(fields[0])
+			sections = append(sections, section)
+			if section == "virtualhost" {
+				host = &vhost{source: fmt.Sprintf("%s:%d", name, l.number)}
+			} else if host != nil && section != "directory" && section != "filesmatch" && section != "files" {
+				host.unsupported = append(host.unsupported, fmt.Sprintf("baris %d: section %s", l.number, line))
+			}
+			continue
+		}
+		if host == nil {
+			continue
+		}
+
+		fields := splitApacheArgs(line)
+		directive := strings.ToLower(fields[0])
+		args := fields[1:]
+		translateApacheDirective(host, directive, args, l.number, line)
+	}
+
+	if len(sections) > 0 {
+		return nil, fmt.Errorf("section <%s> tidak ditutup", sections[len(sections)-1])
+	}
+	return hosts, nil
+}
+
+// translateApacheDirective menerjemahkan satu direktif di dalam VirtualHost
+func translateApacheDirective(host *vhost, directive string, args []string, number int, line string) {
+	switch directive {
+	case "servername":
+		if len(args) > 0 {
+			host.domains = append([]string{args[0]}, host.domains...)
+		}
+	case "serveralias":
+		host.domains = append(host.domains, args...)
+	case "documentroot":
+		if len(args) > 0 {
+			host.root = args[0]
+		}
+	case "sethandler":
+		// SetHandler "proxy:unix:/run/php/php8.1-fpm.sock|fcgi://localhost"
+		if len(args) > 0 && strings.Contains(args[0], "fcgi://") {
+			if version := phpVersionFrom(args[0]); version != "" {
+				host.phpVersion = version
+				return
+			}
+		}
+		host.unsupported = append(host.unsupported, fmt.Sprintf("baris %d: %s", number, line))
+	case "proxypassmatch":
+		if len(args) > 1 && strings.Contains(args[1], "fcgi://") {
+			if version := phpVersionFrom(strings.Join(args, " ")); version != "" {
+				host.phpVersion = version
+				return
+			}
+		}
+		host.unsupported = append(host.unsupported, fmt.Sprintf("baris %d: %s", number, line))
+	case "proxypass":
+		if len(args) < 2 || args[0] != "/" {
+			host.unsupported = append(host.unsupported, fmt.Sprintf("baris %d: %s", number, line))
+			return
+		}
+		target, err := apacheProxyTarget(args[1])
+		if err != nil {
+			host.unsupported = append(host.unsupported, fmt.Sprintf("baris %d: %s", number, err))
+			return
+		}
+		host.proxyTarget = target
+	case "redirect", "redirectpermanent":
+		translateApacheRedirect(host, directive, args, number, line)
+	case "rewriterule":
+		// Pola umum certbot: RewriteRule ^ https://%{SERVER_NAME}%{REQUEST_URI} [END,NE,R=permanent]
+		if len(args) > 1 && (strings.HasPrefix(args[1], "https://%{SERVER_NAME}") || strings.HasPrefix(args[1], "https://%{HTTP_HOST}")) {
+			host.httpsRedirectOnly = true
+			return
+		}
+		host.unsupported = append(host.unsupported, fmt.Sprintf("baris %d: %s", number, line))
+	case "allowoverride":
+		if len(args) > 0 && strings.ToLower(args[0]) != "none" {
+			host.notes = append(host.notes, "file .htaccess tidak didukung oleh Caddy, periksa aturan di dalamnya secara manual")
+		}
+	case "sslcertificatefile":
+		host.notes = append(host.notes, "sertifikat Apache diabaikan, Caddy mengelola HTTPS otomatis (lihat 'site tls set')")
+	default:
+		if !apacheIgnored[directive] {
+			host.unsupported = append(host.unsupported, fmt.Sprintf("baris %d: %s", number, line))
+		}
+	}
+}
+
+// translateApacheRedirect menerjemahkan Redirect dan RedirectPermanent
+func translateApacheRedirect(host *vhost, directive string, args []string, number int, line string) {
+	permanent := directive == "redirectpermanent"
+	if directive == "redirect" && len(args) == 3 {
+		status := strings.ToLower(args[0])
+		permanent = status == "permanent" || status == "301" || status == "308"
+		args = args[1:]
+	}
+	if len(args) != 2 || args[0] != "/" {
+		host.unsupported = append(host.unsupported, fmt.Sprintf("baris %d: %s", number, line))
+		return
+	}
+
+	target := args[1]
+	if len(host.domains) > 0 && strings.TrimSuffix(target, "/") == "https://"+host.domains[0] {
+		host.httpsRedirectOnly = true
+		return
+	}
+	host.redirectTarget = target
+	host.redirectPermanent = permanent
+}
+
+// apacheProxyTarget mengubah argumen ProxyPass menjadi target reverse_proxy
+func apacheProxyTarget(value string) (string, error) {
+	if strings.HasPrefix(value, "unix:") {
+		return "", fmt.Errorf("ProxyPass ke socket unix %s", value)
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("ProxyPass tidak valid: %s", value)
+	}
+	if u.Path != "" && u.Path != "/" {
+		return "", fmt.Errorf("ProxyPass dengan path %s", value)
+	}
+	if u.Scheme == "https" {
+		return "https://" + u.Host, nil
+	}
+	return u.Host, nil
+}
+
+// apacheLine adalah satu baris logis konfigurasi Apache
+type apacheLine struct {
+	text   string
+	number int
+}
+
+// joinApacheLines menggabungkan baris yang diakhiri garis miring terbalik
+func joinApacheLines(content string) []apacheLine {
+	lines := []apacheLine{}
+	current := ""
+	start := 0
+
+	for i, raw := range strings.Split(content, "\n") {
+		if current == "" {
+			start = i + 1
+		}
+		raw = strings.TrimRight(raw, "\r")
+		if strings.HasSuffix(raw, "\\") {
+			current += strings.TrimSuffix(raw, "\\") + " "
+			continue
+		}
+		lines = append(lines, apacheLine{text: current + raw, number: start})
+		current = ""
+	}
+	if current != "" {
+		lines = append(lines, apacheLine{text: current, number: start})
+	}
+
+	return lines
+}
+
+// splitApacheArgs memecah baris direktif dengan memperhatikan tanda kutip
+func splitApacheArgs(line string) []string {
+	args := []string{}
+	var b strings.Builder
+	var quote byte
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && (c == ' ' || c == '\t'):
+			if b.Len() > 0 {
+				args = append(args, b.String())
+				b.Reset()
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	if b.Len() > 0 {
+		args = append(args, b.String())
+	}
+
+	return args
+}
@@ -0,0 +1,299 @@
+package importer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/doko89/webpanel/internal/module"
+	"github.com/doko89/webpanel/internal/proxy"
+	"github.com/doko89/webpanel/internal/site"
+	"github.com/doko89/webpanel/pkg/caddy"
+)
+
+const (
+	siteConfigDir = "/etc/caddy/sites.d"
+)
+
+// Options controls how imported virtual hosts are translated
+type Options struct {
+	DryRun   bool // hanya tampilkan hasil terjemahan tanpa menulis konfigurasi
+	KeepRoot bool // gunakan direktori root asli alih-alih /apps/sites/<domain>
+}
+
+// vhost adalah hasil terjemahan satu server block atau VirtualHost
+type vhost struct {
+	source            string
+	domains           []string
+	root              string
+	phpVersion        string
+	proxyTarget       string
+	redirectTarget    string
+	redirectPermanent bool
+	httpsRedirectOnly bool
+	notes             []string
+	unsupported       []string
+}
+
+var phpVersionPattern = regexp.MustCompile(`php(\d+\.\d+)`)
+
+// Nginx imports nginx server blocks from a file or directory
+func Nginx(path string, opts Options) {
+	fmt.Printf("Importing nginx configuration from: %s\n", path)
+	run(path, opts, parseNginx)
+}
+
+// Apache imports Apache virtual hosts from a file or directory
+func Apache(path string, opts Options) {
+	fmt.Printf("Importing Apache configuration from: %s\n", path)
+	run(path, opts, parseApache)
+}
+
+// run membaca semua file konfigurasi, menerjemahkannya dan membuat situs webpanel
+func run(path string, opts Options, parse func(name, content string) ([]*vhost, error)) {
+	files, err := collectFiles(path)
+	if err != nil {
+		fmt.Printf("Error: Tidak dapat membaca %s: %s\n", path, err)
+		return
+	}
+
+	hosts := []*vhost{}
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			fmt.Printf("Peringatan: Tidak dapat membaca %s: %s\n", file, err)
+			continue
+		}
+		parsed, err := parse(file, string(content))
+		if err != nil {
+			fmt.Printf("Peringatan: Tidak dapat mengurai %s: %s\n", file, err)
+			continue
+		}
+		hosts = append(hosts, parsed...)
+	}
+
+	hosts = mergeHosts(hosts)
+	if len(hosts) == 0 {
+		fmt.Println("Tidak ada virtual host yang ditemukan")
+		return
+	}
+
+	created := []string{}
+	createdDirs := []string{}
+	failed := 0
+	for _, host := range hosts {
+		configPath, createdDir, err := apply(host, opts)
+		if err != nil {
+			fmt.Printf("! %s (%s): %s\n", host.domains[0], host.source, err)
+			failed++
+			if createdDir != "" {
+				os.RemoveAll(createdDir)
+			}
+		} else if configPath != "" {
+			created = append(created, configPath)
+			if createdDir != "" {
+				createdDirs = append(createdDirs, createdDir)
+			}
+		}
+
+		for _, note := range host.notes {
+			fmt.Printf("    catatan: %s\n", note)
+		}
+		for _, item := range host.unsupported {
+			fmt.Printf("    tidak diterjemahkan: %s\n", item)
+		}
+	}
+
+	fmt.Printf("\nRingkasan: %d dibuat, %d gagal, %d virtual host diperiksa\n", len(created), failed, len(hosts))
+	if opts.DryRun || len(created) == 0 {
+		return
+	}
+
+	// Validasi sekali untuk semua konfigurasi baru, batalkan jika tidak valid
+	if err := caddy.ValidateConfig(); err != nil {
+		fmt.Printf("Error: Konfigurasi hasil impor tidak valid, perubahan dibatalkan: %s\n", err)
+		for _, configPath := range created {
+			os.Remove(configPath)
+		}
+		// Hapus juga direktori root yang baru dibuat oleh impor ini
+		for _, dir := range createdDirs {
+			os.RemoveAll(dir)
+		}
+		return
+	}
+
+	// Muat ulang Caddy
+	if err := caddy.Reload(); err != nil {
+		fmt.Printf("Peringatan: Tidak dapat memuat ulang Caddy: %s\n", err)
+	}
+}
+
+// apply membuat situs, proxy atau pengalihan untuk satu virtual host dan
+// mengembalikan path konfigurasi beserta direktori teratas yang baru dibuat
+func apply(host *vhost, opts Options) (string, string, error) {
+	domain := host.domains[0]
+	for _, alias := range host.domains[1:] {
+		host.unsupported = append(host.unsupported, fmt.Sprintf("alias %s (buat situs atau pengalihan terpisah)", alias))
+	}
+
+	if host.onlyRedirectsToHTTPS() {
+		fmt.Printf("- %s: pengalihan HTTP ke HTTPS dilewati (otomatis oleh Caddy)\n", domain)
+		return "", "", nil
+	}
+	if existing, ok := caddy.FindSiteConfig(domain); ok {
+		return "", "", fmt.Errorf("konfigurasi sudah ada di %s", existing.Path)
+	}
+
+	switch {
+	case host.redirectTarget != "":
+		fmt.Printf("+ redirect %s -> %s\n", domain, host.redirectTarget)
+		if opts.DryRun {
+			return "", "", nil
+		}
+		if err := site.CreateRedirect(domain, host.redirectTarget, host.redirectPermanent); err != nil {
+			return "", "", err
+		}
+		return filepath.Join(siteConfigDir, "redirect."+domain+".conf"), "", nil
+
+	case host.proxyTarget != "":
+		fmt.Printf("+ proxy %s -> %s\n", domain, host.proxyTarget)
+		if opts.DryRun {
+			return "", "", nil
+		}
+		if err := proxy.Create(proxy.Config{Domain: domain, Upstreams: []string{host.proxyTarget}}); err != nil {
+			return "", "", err
+		}
+		return filepath.Join(siteConfigDir, "proxy."+domain+".conf"), "", nil
+
+	default:
+		modules := []string{}
+		description := "file server"
+		if host.phpVersion != "" {
+			phpModule := "php" + host.phpVersion
+			if !module.IsAvailable(phpModule) {
+				return "", "", fmt.Errorf("modul %s tidak tersedia, instal PHP %s terlebih dahulu", phpModule, host.phpVersion)
+			}
+			modules = append(modules, phpModule)
+			description = "PHP " + host.phpVersion
+		}
+
+		root := ""
+		if opts.KeepRoot {
+			root = host.root
+		} else if host.root != "" {
+			host.notes = append(host.notes, fmt.Sprintf("salin isi %s ke /apps/sites/%s", host.root, domain))
+		}
+
+		fmt.Printf("+ site %s (%s)\n", domain, description)
+		if opts.DryRun {
+			return "", "", nil
+		}
+		dirRoot := root
+		if dirRoot == "" {
+			dirRoot = filepath.Join("/apps/sites", domain)
+		}
+		createdDir := missingDir(dirRoot)
+		if err := site.Create(domain, root, modules); err != nil {
+			return "", createdDir, err
+		}
+		return filepath.Join(siteConfigDir, domain+".conf"), createdDir, nil
+	}
+}
+
+// mergeHosts menggabungkan blok untuk domain yang sama, misalnya blok port 80 dan 443
+func mergeHosts(hosts []*vhost) []*vhost {
+	byDomain := map[string]*vhost{}
+	order := []string{}
+
+	for _, host := range hosts {
+		if len(host.domains) == 0 {
+			continue
+		}
+		domain := host.domains[0]
+		existing, ok := byDomain[domain]
+		if !ok {
+			byDomain[domain] = host
+			order = append(order, domain)
+			continue
+		}
+		// Blok yang mengalihkan ke HTTPS digantikan oleh blok HTTPS yang berisi konten
+		if existing.httpsRedirectOnly {
+			byDomain[domain] = host
+		} else if !host.httpsRedirectOnly {
+			existing.unsupported = append(existing.unsupported, fmt.Sprintf("blok duplikat untuk %s di %s", domain, host.source))
+		}
+	}
+
+	result := []*vhost{}
+	for _, domain := range order {
+		result = append(result, byDomain[domain])
+	}
+	return result
+}
+
+// onlyRedirectsToHTTPS memeriksa apakah blok hanya mengalihkan HTTP ke HTTPS tanpa konten lain
+func (h *vhost) onlyRedirectsToHTTPS() bool {
+	return h.httpsRedirectOnly && h.root == "" && h.phpVersion == "" && h.proxyTarget == "" && h.redirectTarget == ""
+}
+
+// missingDir mengembalikan direktori teratas dari path yang belum ada, atau
+// string kosong jika path sudah ada
+func missingDir(path string) string {
+	missing := ""
+	for dir := filepath.Clean(path); ; dir = filepath.Dir(dir) {
+		if _, err := os.Lstat(dir); err == nil {
+			return missing
+		}
+		missing = dir
+		if dir == filepath.Dir(dir) {
+			return missing
+		}
+	}
+}
+
+// collectFiles mengembalikan file konfigurasi dari path file atau direktori
+func collectFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, entry := range entries {
+		// Lewati direktori dan file cadangan editor
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), "~") || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		files = append(files, filepath.Join(path, entry.Name()))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// phpVersionFrom mengekstrak versi PHP dari path socket atau handler PHP-FPM
+func phpVersionFrom(value string) string {
+	matches := phpVersionPattern.FindStringSubmatch(value)
+	if len(matches) > 1 {
+		return matches[1]
+	}
+	return ""
+}
+
+// unquote menghapus tanda kutip di sekitar nilai
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
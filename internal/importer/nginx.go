@@ -0,0 +1,315 @@
+package importer
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// nginxDirective adalah satu direktif nginx beserta bloknya
+type nginxDirective struct {
+	name  string
+	args  []string
+	line  int
+	block []*nginxDirective
+}
+
+// nginxToken adalah satu token dari file konfigurasi nginx
+type nginxToken struct {
+	text   string
+	line   int
+	quoted bool
+}
+
+// nginxIgnored adalah direktif yang tidak memerlukan padanan di Caddy
+var nginxIgnored = map[string]bool{
+	"listen":      true,
+	"index":       true,
+	"access_log":  true,
+	"error_log":   true,
+	"charset":     true,
+	"server_name": true,
+	"root":        true,
+	"autoindex":   true,
+	"sendfile":    true,
+}
+
+// parseNginx mengurai server block dari konfigurasi nginx
+func parseNginx(name, content string) ([]*vhost, error) {
+	tokens, err := tokenizeNginx(content)
+	if err != nil {
+		return nil, err
+	}
+	directives, rest, err := buildNginxTree(tokens)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("baris %d: kurung kurawal penutup tidak terduga", rest[0].line)
+	}
+
+	hosts := []*vhost{}
+	var walk func(list []*nginxDirective)
+	walk = func(list []*nginxDirective) {
+		for _, d := range list {
+			switch d.name {
+			case "server":
+				if host := translateNginxServer(name, d); host != nil {
+					hosts = append(hosts, host)
+				}
+			case "http":
+				walk(d.block)
+			}
+		}
+	}
+	walk(directives)
+
+	return hosts, nil
+}
+
+// tokenizeNginx memecah konfigurasi nginx menjadi token
+func tokenizeNginx(content string) ([]nginxToken, error) {
+	tokens := []nginxToken{}
+	line := 1
+	i := 0
+
+	for i < len(content) {
+		c := content[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '#':
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+		case c == ';' || c == '{' || c == '}':
+			tokens = append(tokens, nginxToken{text: string(c), line: line})
+			i++
+		case c == '"' || c == '\'':
+			start := line
+			i++
+			var b strings.Builder
+			for i < len(content) && content[i] != c {
+				if content[i] == '\\' && i+1 < len(content) {
+					i++
+				}
+				if content[i] == '\n' {
+					line++
+				}
+				b.WriteByte(content[i])
+				i++
+			}
+			if i >= len(content) {
+				return nil, fmt.Errorf("baris %d: tanda kutip tidak ditutup", start)
+			}
+			i++
+			tokens = append(tokens, nginxToken{text: b.String(), line: start, quoted: true})
+		default:
+			start := i
+			for i < len(content) && !strings.ContainsRune(" \t\r\n;{}", rune(content[i])) {
+				i++
+			}
+			tokens = append(tokens, nginxToken{text: content[start:i], line: line})
+		}
+	}
+
+	return tokens, nil
+}
+
+// buildNginxTree menyusun token menjadi pohon direktif sampai kurung kurawal penutup
+func buildNginxTree(tokens []nginxToken) ([]*nginxDirective, []nginxToken, error) {
+	directives := []*nginxDirective{}
+
+	for len(tokens) > 0 {
+		tok := tokens[0]
+		if tok.text == "}" && !tok.quoted {
+			return directives, tokens, nil
+		}
+
+		d := &nginxDirective{name: tok.text, line: tok.line}
+		tokens = tokens[1:]
+		for {
+			if len(tokens) == 0 {
+				return nil, nil, fmt.Errorf("baris %d: direktif %s tidak diakhiri", d.line, d.name)
+			}
+			tok = tokens[0]
+			tokens = tokens[1:]
+			if tok.quoted {
+				d.args = append(d.args, tok.text)
+				continue
+			}
+			if tok.text == ";" {
+				break
+			}
+			if tok.text == "{" {
+				block, rest, err := buildNginxTree(tokens)
+				if err != nil {
+					return nil, nil, err
+				}
+				if len(rest) == 0 {
+					return nil, nil, fmt.Errorf("baris %d: blok %s tidak ditutup", d.line, d.name)
+				}
+				d.block = block
+				tokens = rest[1:]
+				break
+			}
+			if tok.text == "}" {
+				return nil, nil, fmt.Errorf("baris %d: kurung kurawal penutup tidak terduga", tok.line)
+			}
+			d.args = append(d.args, tok.text)
+		}
+		directives = append(directives, d)
+	}
+
+	return directives, nil, nil
+}
+
+// translateNginxServer menerjemahkan satu server block
+func translateNginxServer(file string, server *nginxDirective) *vhost {
+	host := &vhost{source: fmt.Sprintf("%s:%d", file, server.line)}
+
+	for _, d := range server.block {
+		switch d.name {
+		case "server_name":
+			for _, name := range d.args {
+				// Lewati server default dan nama berbasis regex
+				if name == "_" || name == "" || strings.HasPrefix(name, "~") {
+					continue
+				}
+				host.domains = append(host.domains, name)
+			}
+		case "root":
+			if len(d.args) > 0 {
+				host.root = d.args[0]
+			}
+		}
+	}
+	if len(host.domains) == 0 {
+		return nil
+	}
+
+	for _, d := range server.block {
+		switch {
+		case nginxIgnored[d.name]:
+		case d.name == "return":
+			translateNginxReturn(host, d)
+		case d.name == "location":
+			translateNginxLocation(host, d)
+		case strings.HasPrefix(d.name, "ssl_"):
+			if d.name == "ssl_certificate" {
+				host.notes = append(host.notes, "sertifikat nginx diabaikan, Caddy mengelola HTTPS otomatis (lihat 'site tls set')")
+			}
+		case d.name == "include":
+			host.unsupported = append(host.unsupported, fmt.Sprintf("baris %d: include %s", d.line, strings.Join(d.args, " ")))
+		default:
+			host.unsupported = append(host.unsupported, fmt.Sprintf("baris %d: %s %s", d.line, d.name, strings.Join(d.args, " ")))
+		}
+	}
+
+	return host
+}
+
+// translateNginxReturn menerjemahkan direktif return menjadi pengalihan
+func translateNginxReturn(host *vhost, d *nginxDirective) {
+	if len(d.args) < 2 || (d.args[0] != "301" && d.args[0] != "302" && d.args[0] != "307" && d.args[0] != "308") {
+		host.unsupported = append(host.unsupported, fmt.Sprintf("baris %d: return %s", d.line, strings.Join(d.args, " ")))
+		return
+	}
+
+	target := d.args[1]
+	// Pengalihan ke HTTPS untuk domain yang sama sudah dilakukan otomatis oleh Caddy
+	for _, prefix := range []string{"https://$host", "https://$server_name", "https://$http_host", "https://" + host.domains[0]} {
+		if target == prefix+"$request_uri" || target == prefix+"$uri" {
+			host.httpsRedirectOnly = true
+			return
+		}
+	}
+
+	target = strings.TrimSuffix(target, "$request_uri")
+	target = strings.Replace(target, "$server_name", host.domains[0], -1)
+	if strings.Contains(target, "$") {
+		host.unsupported = append(host.unsupported, fmt.Sprintf("baris %d: return dengan variabel %s", d.line, d.args[1]))
+		return
+	}
+	host.redirectTarget = target
+	host.redirectPermanent = d.args[0] == "301" || d.args[0] == "308"
+}
+
+// translateNginxLocation menerjemahkan blok location untuk PHP dan proxy
+func translateNginxLocation(host *vhost, location *nginxDirective) {
+	path := strings.Join(location.args, " ")
+
+	for _, d := range location.block {
+		switch d.name {
+		case "fastcgi_pass":
+			if len(d.args) == 0 {
+				continue
+			}
+			version := phpVersionFrom(d.args[0])
+			if version == "" {
+				host.unsupported = append(host.unsupported, fmt.Sprintf("baris %d: versi PHP tidak diketahui dari fastcgi_pass %s", d.line, d.args[0]))
+				continue
+			}
+			host.phpVersion = version
+
+		case "proxy_pass":
+			if len(d.args) == 0 {
+				continue
+			}
+			if path != "/" {
+				host.unsupported = append(host.unsupported, fmt.Sprintf("baris %d: proxy_pass untuk location %s", d.line, path))
+				continue
+			}
+			target, err := nginxProxyTarget(d.args[0])
+			if err != nil {
+				host.unsupported = append(host.unsupported, fmt.Sprintf("baris %d: %s", d.line, err))
+				continue
+			}
+			host.proxyTarget = target
+
+		case "return":
+			if path == "/" {
+				translateNginxReturn(host, d)
+			} else {
+				host.unsupported = append(host.unsupported, fmt.Sprintf("baris %d: return di location %s", d.line, path))
+			}
+
+		case "try_files", "include", "index", "fastcgi_param", "fastcgi_index", "fastcgi_split_path_info",
+			"proxy_set_header", "proxy_http_version", "proxy_redirect":
+			// Ditangani otomatis oleh php_fastcgi dan reverse_proxy di Caddy
+
+		case "deny", "allow":
+			// Aturan umum untuk menyembunyikan .htaccess tidak diperlukan
+			if !strings.Contains(path, ".ht") {
+				host.unsupported = append(host.unsupported, fmt.Sprintf("baris %d: %s %s di location %s", d.line, d.name, strings.Join(d.args, " "), path))
+			}
+
+		default:
+			host.unsupported = append(host.unsupported, fmt.Sprintf("baris %d: %s %s di location %s", d.line, d.name, strings.Join(d.args, " "), path))
+		}
+	}
+}
+
+// nginxProxyTarget mengubah argumen proxy_pass menjadi target reverse_proxy
+func nginxProxyTarget(value string) (string, error) {
+	if strings.HasPrefix(value, "http://unix:") {
+		return "", fmt.Errorf("proxy_pass ke socket unix %s", value)
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("proxy_pass tidak valid: %s", value)
+	}
+	if u.Path != "" && u.Path != "/" {
+		return "", fmt.Errorf("proxy_pass dengan path %s", value)
+	}
+	if strings.Contains(u.Host, "$") || (!strings.Contains(u.Host, ":") && !strings.Contains(u.Host, ".") && u.Host != "localhost") {
+		return "", fmt.Errorf("proxy_pass ke upstream bernama %s", value)
+	}
+	if u.Scheme == "https" {
+		return "https://" + u.Host, nil
+	}
+	return u.Host, nil
+}
@@ -0,0 +1,202 @@
+package module
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/doko89/webpanel/pkg/caddy"
+	"github.com/doko89/webpanel/pkg/caddyfile"
+)
+
+// SiteTypes are the site types that can be selected with Selector.Type
+var SiteTypes = []string{"php", "static", caddy.KindProxy, caddy.KindRedirect}
+
+// Selector chooses the sites of a bulk module operation: every site, the
+// sites of one type, or the domains listed in a file
+type Selector struct {
+	All         bool
+	Type        string
+	DomainsFrom string
+}
+
+// siteResult adalah hasil operasi modul untuk satu situs
+type siteResult struct {
+	domain  string
+	path    string
+	changed bool
+	status  string
+}
+
+// EnableMany enables a module for every selected site, validating and
+// reloading Caddy only once
+func EnableMany(module string, selector Selector, values []string) {
+	fmt.Printf("Enabling module %s for %s\n", module, selector)
+	req, err := prepareEnable(module, values)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	applyMany(selector, "diaktifkan", "sudah aktif", func(site *caddyfile.Node) (bool, error) {
+		return req.apply(site)
+	})
+	// Situs yang sudah memakai modul mempertahankan parameter yang tidak diberikan
+	if len(req.values) > 0 {
+		fmt.Printf("Parameter yang diatur: %s\n", strings.Join(req.values, ", "))
+	}
+}
+
+// DisableMany disables a module for every selected site, validating and
+// reloading Caddy only once
+func DisableMany(module string, selector Selector) {
+	fmt.Printf("Disabling module %s for %s\n", module, selector)
+	applyMany(selector, "dinonaktifkan", "tidak aktif", func(site *caddyfile.Node) (bool, error) {
+		return disableIn(site, module)
+	})
+}
+
+// String describes the selected sites
+func (s Selector) String() string {
+	switch {
+	case s.All:
+		return "all sites"
+	case s.Type != "":
+		return s.Type + " sites"
+	default:
+		return "domains from " + s.DomainsFrom
+	}
+}
+
+// applyMany menerapkan perubahan ke setiap situs terpilih, menulis semua
+// perubahan sekaligus dan menampilkan tabel hasil per situs
+func applyMany(selector Selector, done, unchanged string, change func(site *caddyfile.Node) (bool, error)) {
+	configs, err := selectSites(selector)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	if len(configs) == 0 {
+		fmt.Println("Tidak ada situs yang cocok")
+		return
+	}
+
+	results := []*siteResult{}
+	files := map[string][]byte{}
+	for _, config := range configs {
+		result := &siteResult{domain: config.Domain, path: config.Path}
+		results = append(results, result)
+
+		if config.Path == "" {
+			result.status = "gagal: domain tidak ditemukan"
+			continue
+		}
+		file, site, err := caddyfile.LoadSite(config.Path)
+		if err != nil {
+			result.status = "gagal: " + err.Error()
+			continue
+		}
+		if selector.Type != "" && siteType(config, site) != selector.Type {
+			result.status = "dilewati: bukan situs " + selector.Type
+			continue
+		}
+		changed, err := change(site)
+		if err != nil {
+			result.status = "gagal: " + err.Error()
+			continue
+		}
+		if !changed {
+			result.status = unchanged
+			continue
+		}
+		result.changed, result.status = true, done
+		files[result.path] = []byte(file.String())
+	}
+
+	// Validasi sekali untuk semua situs, semua file dipulihkan jika tidak valid
+	if len(files) > 0 {
+		if err := caddy.ReplaceConfigs(files); err != nil {
+			for _, result := range results {
+				if result.changed {
+					result.status = "dibatalkan"
+				}
+			}
+			printResults(results)
+			fmt.Printf("Error: Perubahan dibatalkan: %s\n", err)
+			return
+		}
+		// Muat ulang Caddy
+		if err := caddy.Reload(); err != nil {
+			fmt.Printf("Peringatan: Tidak dapat memuat ulang Caddy: %s\n", err)
+		}
+	}
+
+	printResults(results)
+	fmt.Printf("%d situs diubah, %d situs diperiksa\n", len(files), len(results))
+}
+
+// printResults menampilkan tabel hasil per situs
+func printResults(results []*siteResult) {
+	width := len("DOMAIN")
+	for _, result := range results {
+		if len(result.domain) > width {
+			width = len(result.domain)
+		}
+	}
+	fmt.Printf("%-*s  %s\n", width, "DOMAIN", "HASIL")
+	for _, result := range results {
+		fmt.Printf("%-*s  %s\n", width, result.domain, result.status)
+	}
+}
+
+// selectSites mengembalikan konfigurasi situs sesuai pilihan; domain dari file
+// yang tidak dikonfigurasi dikembalikan tanpa Path
+func selectSites(selector Selector) ([]caddy.SiteConfig, error) {
+	if selector.Type != "" && !contains(SiteTypes, selector.Type) {
+		return nil, fmt.Errorf("tipe situs tidak dikenal: %s (gunakan %s)", selector.Type, strings.Join(SiteTypes, ", "))
+	}
+	if selector.DomainsFrom == "" {
+		configs, err := caddy.SiteConfigs()
+		if err != nil {
+			return nil, fmt.Errorf("tidak dapat membaca direktori konfigurasi: %w", err)
+		}
+		return configs, nil
+	}
+
+	content, err := ioutil.ReadFile(selector.DomainsFrom)
+	if err != nil {
+		return nil, fmt.Errorf("tidak dapat membaca daftar domain: %w", err)
+	}
+	configs := []caddy.SiteConfig{}
+	seen := []string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		// Satu domain per baris, baris kosong dan komentar dilewati
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || contains(seen, line) {
+			continue
+		}
+		seen = append(seen, line)
+		config, ok := caddy.FindSiteConfig(line)
+		if !ok {
+			config = caddy.SiteConfig{Domain: line}
+		}
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
+
+// siteType menentukan tipe situs: proxy dan redirect dari jenis konfigurasinya,
+// php jika menggunakan modul php atau php_fastcgi, selain itu static
+func siteType(config caddy.SiteConfig, site *caddyfile.Node) string {
+	if config.Kind != caddy.KindSite {
+		return config.Kind
+	}
+	if site.Find("php_fastcgi") != nil {
+		return "php"
+	}
+	for _, module := range enabledModules(site) {
+		if strings.HasPrefix(module, "php") {
+			return "php"
+		}
+	}
+	return "static"
+}
@@ -0,0 +1,275 @@
+package module
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/doko89/webpanel/pkg/caddy"
+	"github.com/doko89/webpanel/pkg/caddyfile"
+)
+
+// defaultFS berisi modul bawaan beserta daftar versi dan checksum-nya
+//
+//go:embed defaults
+var defaultFS embed.FS
+
+// Builtin is a module shipped with webpanel
+type Builtin struct {
+	Name     string
+	Version  string
+	Checksum string
+	Content  []byte
+}
+
+// builtinModules membaca modul bawaan dan memastikan checksum-nya tercatat
+// di daftar versi
+func builtinModules() ([]Builtin, map[string]string, error) {
+	known, err := knownChecksums()
+	if err != nil {
+		return nil, nil, err
+	}
+	entries, err := defaultFS.ReadDir("defaults")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	builtins := []Builtin{}
+	for _, entry := range entries {
+		if entry.Name() == "versions" {
+			continue
+		}
+		content, err := defaultFS.ReadFile("defaults/" + entry.Name())
+		if err != nil {
+			return nil, nil, err
+		}
+		builtin := Builtin{Name: entry.Name(), Checksum: checksum(content), Content: content}
+		version, ok := known[builtin.Name+" "+builtin.Checksum]
+		if !ok {
+			return nil, nil, fmt.Errorf("checksum modul bawaan %s tidak tercatat di defaults/versions", builtin.Name)
+		}
+		builtin.Version = version
+		builtins = append(builtins, builtin)
+	}
+	sort.Slice(builtins, func(i, j int) bool { return builtins[i].Name < builtins[j].Name })
+	return builtins, known, nil
+}
+
+// knownChecksums membaca daftar versi, kuncinya "nama checksum" dan nilainya versi
+func knownChecksums() (map[string]string, error) {
+	content, err := defaultFS.ReadFile("defaults/versions")
+	if err != nil {
+		return nil, err
+	}
+	known := map[string]string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("baris tidak valid di defaults/versions: %s", line)
+		}
+		known[fields[0]+" "+fields[2]] = fields[1]
+	}
+	return known, nil
+}
+
+// InstallDefaults writes the built-in modules that do not exist yet. Existing
+// modules are never overwritten; use Upgrade to update them.
+func InstallDefaults() ([]string, error) {
+	builtins, _, err := builtinModules()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(moduleDir, 0755); err != nil {
+		return nil, fmt.Errorf("tidak dapat membuat direktori modul: %w", err)
+	}
+
+	installed := []string{}
+	for _, builtin := range builtins {
+		path := filepath.Join(moduleDir, builtin.Name)
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		if err := caddy.WriteFileAtomic(path, builtin.Content, 0644); err != nil {
+			return installed, fmt.Errorf("tidak dapat menulis modul %s: %w", builtin.Name, err)
+		}
+		installed = append(installed, builtin.Name)
+	}
+	return installed, nil
+}
+
+// Upgrade installs missing built-in modules and updates the ones that still
+// match a shipped version. Locally modified modules are shown as a diff and
+// only replaced after confirmation, or always kept with keepModified.
+func Upgrade(keepModified bool) {
+	fmt.Println("Upgrading built-in modules:")
+	builtins, known, err := builtinModules()
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+
+	files := map[string][]byte{}
+	statuses := []string{}
+	reader := bufio.NewReader(os.Stdin)
+	for _, builtin := range builtins {
+		path := filepath.Join(moduleDir, builtin.Name)
+		current, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			statuses = append(statuses, fmt.Sprintf("%s: gagal dibaca: %s", builtin.Name, err))
+			continue
+		}
+
+		var status string
+		switch version, untouched := known[builtin.Name+" "+checksum(current)]; {
+		case os.IsNotExist(err):
+			files[path] = builtin.Content
+			status = "dipasang (versi " + builtin.Version + ")"
+		case version == builtin.Version:
+			status = "sudah terbaru (versi " + builtin.Version + ")"
+		case untouched:
+			files[path] = builtin.Content
+			status = "diperbarui dari versi " + version + " ke " + builtin.Version
+		default:
+			// Modul diubah secara lokal, tampilkan perbedaannya sebelum diganti
+			fmt.Printf("\nModul %s diubah secara lokal, perbedaan dengan versi bawaan %s:\n", builtin.Name, builtin.Version)
+			if err := showDiff(builtin.Name, current, builtin.Content); err != nil {
+				fmt.Printf("Peringatan: Tidak dapat menampilkan perbedaan: %s\n", err)
+			}
+			status = "dipertahankan (diubah secara lokal)"
+			if !keepModified {
+				fmt.Printf("Ganti modul %s dengan versi bawaan? (y/N): ", builtin.Name)
+				response, _ := reader.ReadString('\n')
+				if strings.ToLower(strings.TrimSpace(response)) == "y" {
+					files[path] = builtin.Content
+					status = "diganti dengan versi " + builtin.Version
+				}
+			}
+		}
+		statuses = append(statuses, builtin.Name+": "+status)
+	}
+
+	// Modul versi lama tidak memiliki parameter, import tanpa argumen di situs
+	// dilengkapi dalam transaksi yang sama agar {args[N]} tidak tertinggal
+	contents := map[string][]byte{}
+	for _, builtin := range builtins {
+		path := filepath.Join(moduleDir, builtin.Name)
+		if content, ok := files[path]; ok {
+			contents[builtin.Name] = content
+		} else if content, err := ioutil.ReadFile(path); err == nil {
+			contents[builtin.Name] = content
+		}
+	}
+	migrated, err := migrateImports(contents, files)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	for _, domain := range migrated {
+		statuses = append(statuses, domain+": import modul dilengkapi dengan nilai default")
+	}
+
+	if len(files) > 0 {
+		// Tulis semua modul sekaligus, semuanya dipulihkan jika konfigurasi menjadi tidak valid
+		if err := caddy.ReplaceConfigs(files); err != nil {
+			fmt.Printf("Error: Perubahan dibatalkan: %s\n", err)
+			return
+		}
+		// Muat ulang Caddy
+		if err := caddy.Reload(); err != nil {
+			fmt.Printf("Peringatan: Tidak dapat memuat ulang Caddy: %s\n", err)
+		}
+	}
+
+	fmt.Println()
+	for _, status := range statuses {
+		fmt.Println("-", status)
+	}
+}
+
+// migrateImports melengkapi import modul berparameter di semua konfigurasi
+// situs dengan nilai default dan menambahkan hasilnya ke files. Mengembalikan
+// domain yang konfigurasinya berubah.
+func migrateImports(modules map[string][]byte, files map[string][]byte) ([]string, error) {
+	params := map[string][]Param{}
+	for name, content := range modules {
+		file, err := caddyfile.Parse(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("modul %s tidak valid: %w", name, err)
+		}
+		if params[name], err = parseParams(name, file); err != nil {
+			return nil, err
+		}
+	}
+
+	configs, err := caddy.SiteConfigs()
+	if err != nil {
+		return nil, fmt.Errorf("tidak dapat membaca konfigurasi situs: %w", err)
+	}
+	migrated := []string{}
+	for _, config := range configs {
+		file, site, err := caddyfile.LoadSite(config.Path)
+		if err != nil {
+			continue
+		}
+		changed := false
+		for _, node := range site.FindAll("import") {
+			args := node.Args()
+			if len(args) == 0 || len(args)-1 >= len(params[args[0]]) {
+				continue
+			}
+			node.Tokens = caddyfile.New(append([]string{"import", args[0]}, completeArgs(params[args[0]], args[1:])...)...).Tokens
+			changed = true
+		}
+		if changed {
+			files[config.Path] = []byte(file.String())
+			migrated = append(migrated, config.Domain)
+		}
+	}
+	return migrated, nil
+}
+
+// showDiff menampilkan perbedaan modul lokal dengan versi bawaan menggunakan diff -u
+func showDiff(name string, current, builtin []byte) error {
+	dir, err := ioutil.TempDir("", "webpanel-diff-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	local, shipped := filepath.Join(dir, "local"), filepath.Join(dir, "builtin")
+	if err := ioutil.WriteFile(local, current, 0644); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(shipped, builtin, 0644); err != nil {
+		return err
+	}
+
+	cmd := exec.Command("diff", "-u", "--label", name+" (lokal)", "--label", name+" (bawaan)", local, shipped)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// diff keluar dengan status 1 jika file berbeda
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// checksum menghitung sha256 isi modul
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
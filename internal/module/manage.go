@@ -0,0 +1,341 @@
+package module
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/doko89/webpanel/pkg/caddy"
+	"github.com/doko89/webpanel/pkg/caddyfile"
+)
+
+// moduleNamePattern adalah aturan nama modul; nama file, nama snippet dan
+// argumen import selalu sama persis, tanpa ekstensi
+var moduleNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// Create adds a module from a file containing a snippet with the same name
+func Create(name, source string) {
+	fmt.Printf("Creating module: %s\n", name)
+	if err := validateName(name); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	if IsAvailable(name) {
+		fmt.Printf("Error: Modul %s sudah ada, gunakan 'module edit' untuk mengubahnya\n", name)
+		return
+	}
+
+	content, err := ioutil.ReadFile(source)
+	if err != nil {
+		fmt.Printf("Error: Tidak dapat membaca file %s: %s\n", source, err)
+		return
+	}
+	content, err = validateModule(name, content)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+
+	// Tulis secara atomik, file baru dihapus jika konfigurasi Caddy menjadi tidak valid
+	if err := caddy.ReplaceConfig(filepath.Join(moduleDir, name), content); err != nil {
+		fmt.Printf("Error: Modul tidak dibuat: %s\n", err)
+		return
+	}
+
+	fmt.Printf("Modul %s berhasil dibuat\n", name)
+}
+
+// Edit opens a module in $EDITOR and replaces it only when the edited
+// snippet passes a test import
+func Edit(name string) {
+	fmt.Printf("Editing module: %s\n", name)
+	if err := validateName(name); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	modulePath := filepath.Join(moduleDir, name)
+	original, err := ioutil.ReadFile(modulePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			fmt.Printf("Error: Modul tidak tersedia: %s\n", name)
+		} else {
+			fmt.Printf("Error: Tidak dapat membaca modul: %s\n", err)
+		}
+		return
+	}
+
+	// Sunting salinan sementara agar modul asli tidak berubah sebelum divalidasi
+	tmp, err := ioutil.TempFile("", "webpanel-module-"+name+"-")
+	if err != nil {
+		fmt.Printf("Error: Tidak dapat membuat file sementara: %s\n", err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(original); err != nil {
+		tmp.Close()
+		fmt.Printf("Error: Tidak dapat menulis file sementara: %s\n", err)
+		return
+	}
+	tmp.Close()
+
+	var content []byte
+	for {
+		if err := runEditor(tmp.Name()); err != nil {
+			fmt.Printf("Error: %s\n", err)
+			return
+		}
+		edited, err := ioutil.ReadFile(tmp.Name())
+		if err != nil {
+			fmt.Printf("Error: Tidak dapat membaca file sementara: %s\n", err)
+			return
+		}
+		if string(edited) == string(original) {
+			fmt.Println("Tidak ada perubahan")
+			return
+		}
+
+		content, err = validateModule(name, edited)
+		if err == nil {
+			break
+		}
+		fmt.Printf("Error: %s\n", err)
+		fmt.Print("Sunting lagi? (y/N): ")
+		var response string
+		fmt.Scanln(&response)
+		if strings.ToLower(response) != "y" {
+			fmt.Println("Perubahan dibatalkan")
+			return
+		}
+	}
+
+	// Validasi seluruh konfigurasi, modul lama dipulihkan jika situs yang memakainya menjadi tidak valid
+	if err := caddy.ReplaceConfig(modulePath, content); err != nil {
+		fmt.Printf("Error: Perubahan dibatalkan: %s\n", err)
+		return
+	}
+
+	users := moduleUsers(name)
+	if len(users) > 0 {
+		// Muat ulang Caddy
+		if err := caddy.Reload(); err != nil {
+			fmt.Printf("Peringatan: Tidak dapat memuat ulang Caddy: %s\n", err)
+		}
+	}
+
+	fmt.Printf("Modul %s berhasil diperbarui\n", name)
+	if len(users) > 0 {
+		fmt.Printf("Digunakan oleh: %s\n", strings.Join(users, ", "))
+	}
+}
+
+// Delete removes a module that is not enabled for any site
+func Delete(name string) {
+	fmt.Printf("Deleting module: %s\n", name)
+	if err := validateName(name); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	if !IsAvailable(name) {
+		fmt.Printf("Error: Modul tidak tersedia: %s\n", name)
+		return
+	}
+	if users := moduleUsers(name); len(users) > 0 {
+		fmt.Printf("Error: Modul %s masih digunakan oleh: %s\n", name, strings.Join(users, ", "))
+		fmt.Println("Nonaktifkan modul dengan 'module disable' terlebih dahulu")
+		return
+	}
+
+	// Konfirmasi penghapusan
+	fmt.Printf("Anda yakin ingin menghapus modul %s? (y/N): ", name)
+	var response string
+	fmt.Scanln(&response)
+	if strings.ToLower(response) != "y" {
+		fmt.Println("Penghapusan dibatalkan")
+		return
+	}
+
+	if err := os.Remove(filepath.Join(moduleDir, name)); err != nil {
+		fmt.Printf("Error: Tidak dapat menghapus modul: %s\n", err)
+		return
+	}
+
+	fmt.Printf("Modul %s berhasil dihapus\n", name)
+}
+
+// validateName memeriksa nama modul sesuai aturan penamaan
+func validateName(name string) error {
+	if !moduleNamePattern.MatchString(name) {
+		return fmt.Errorf("nama modul tidak valid: %s (gunakan huruf kecil, angka, ., - dan _)", name)
+	}
+	if strings.HasSuffix(name, ".conf") {
+		return fmt.Errorf("nama modul tidak boleh berakhiran .conf: %s (nama modul adalah nama file tanpa ekstensi)", name)
+	}
+	return nil
+}
+
+// validateModule memeriksa isi modul dan mencobanya dengan mengimpor snippet
+// ke konfigurasi uji bersama modul yang diperlukannya. Pengujian dengan Caddy
+// dilewati jika plugin yang dibutuhkan belum terpasang. Isi yang dikembalikan
+// sudah diformat ulang.
+func validateModule(name string, content []byte) ([]byte, error) {
+	file, err := caddyfile.Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("modul tidak dapat dibaca: %w", err)
+	}
+	for _, node := range file.Nodes {
+		if len(node.Tokens) > 0 && !node.IsSnippet() {
+			return nil, fmt.Errorf("modul hanya boleh berisi snippet (%s), ditemukan: %s", name, strings.Join(node.Tokens, " "))
+		}
+	}
+	if file.Snippet(name) == nil {
+		return nil, fmt.Errorf("modul harus berisi snippet (%s) yang sama dengan nama modul", name)
+	}
+	meta, err := parseMetadata(name, file)
+	if err != nil {
+		return nil, err
+	}
+	formatted := []byte(file.String())
+
+	// Modul yang diperlukan diimpor lebih dulu, sesuai urutan saat diaktifkan
+	modules, err := testModules(name, file, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	plugins := []string{}
+	for _, module := range modules {
+		for _, plugin := range module.meta.Plugins {
+			if !contains(plugins, plugin) {
+				plugins = append(plugins, plugin)
+			}
+		}
+	}
+	if missing := missingPlugins(plugins); len(missing) > 0 {
+		fmt.Printf("Peringatan: Plugin Caddy %s tidak terpasang, pengujian modul %s dengan Caddy dilewati (lihat 'webpanel module build-caddy')\n",
+			strings.Join(missing, ", "), name)
+		return formatted, nil
+	}
+
+	dir, err := ioutil.TempDir("", "webpanel-module-")
+	if err != nil {
+		return nil, fmt.Errorf("tidak dapat membuat direktori sementara: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Situs uji mengimpor setiap snippet dengan nilai parameter default
+	test := &caddyfile.File{}
+	site := caddyfile.New("http://module-test.localhost")
+	for _, module := range modules {
+		if err := ioutil.WriteFile(filepath.Join(dir, module.name), []byte(module.file.String()), 0644); err != nil {
+			return nil, fmt.Errorf("tidak dapat menulis modul uji: %w", err)
+		}
+		test.Nodes = append(test.Nodes, caddyfile.New("import", filepath.Join(dir, module.name)))
+		site.Add(caddyfile.New(append([]string{"import", module.name}, module.args...)...))
+	}
+	test.Nodes = append(test.Nodes, site.Add(caddyfile.New("respond", "OK")))
+	testPath := filepath.Join(dir, "Caddyfile")
+	if err := ioutil.WriteFile(testPath, []byte(test.String()), 0644); err != nil {
+		return nil, fmt.Errorf("tidak dapat menulis konfigurasi uji: %w", err)
+	}
+	if err := caddy.ValidateFile(testPath); err != nil {
+		return nil, fmt.Errorf("modul gagal diuji: %w", err)
+	}
+	return formatted, nil
+}
+
+// testModule adalah modul yang diimpor ke konfigurasi uji
+type testModule struct {
+	name string
+	file *caddyfile.File
+	meta Metadata
+	args []string
+}
+
+// testModules mengembalikan modul beserta semua modul yang diperlukannya
+// secara berantai, modul yang diperlukan lebih dulu
+func testModules(name string, file *caddyfile.File, meta Metadata) ([]testModule, error) {
+	modules := []testModule{}
+	visiting := map[string]bool{}
+	var add func(name string, file *caddyfile.File, meta Metadata) error
+	add = func(name string, file *caddyfile.File, meta Metadata) error {
+		visiting[name] = true
+		for _, required := range meta.Requires {
+			if visiting[required] {
+				continue
+			}
+			requiredFile, err := caddyfile.Load(filepath.Join(moduleDir, required))
+			if err != nil {
+				if os.IsNotExist(err) {
+					return fmt.Errorf("modul %s memerlukan modul %s yang tidak tersedia", name, required)
+				}
+				return fmt.Errorf("tidak dapat membaca modul %s: %w", required, err)
+			}
+			requiredMeta, err := parseMetadata(required, requiredFile)
+			if err != nil {
+				return err
+			}
+			if err := add(required, requiredFile, requiredMeta); err != nil {
+				return err
+			}
+		}
+
+		params, err := parseParams(name, file)
+		if err != nil {
+			return err
+		}
+		args, err := buildArgs(name, params, nil, nil)
+		if err != nil {
+			return err
+		}
+		modules = append(modules, testModule{name, file, meta, args})
+		return nil
+	}
+	if err := add(name, file, meta); err != nil {
+		return nil, err
+	}
+	return modules, nil
+}
+
+// missingPlugins mengembalikan plugin yang tidak ada di binary Caddy; jika
+// daftar modul Caddy tidak dapat dibaca semua plugin dianggap tidak terpasang
+func missingPlugins(plugins []string) []string {
+	if len(plugins) == 0 {
+		return nil
+	}
+	installed, err := caddy.ListModules()
+	if err != nil {
+		return plugins
+	}
+	missing := []string{}
+	for _, plugin := range plugins {
+		if !contains(installed, plugin) {
+			missing = append(missing, plugin)
+		}
+	}
+	return missing
+}
+
+// runEditor membuka file dengan editor dari $VISUAL atau $EDITOR
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	// Editor boleh berisi argumen, misalnya "code --wait"
+	fields := strings.Fields(editor)
+	cmd := exec.Command(fields[0], append(fields[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %s gagal: %w", editor, err)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package module
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/doko89/webpanel/pkg/caddy"
+	"github.com/doko89/webpanel/pkg/caddyfile"
+)
+
+// defaultWeight adalah bobot urutan modul yang tidak mendeklarasikan weight
+const defaultWeight = 50
+
+// Metadata describes a module through header comments next to its parameters:
+//
+//	# version <number>
+//	# description <text>
+//	# requires <module>...
+//	# conflicts <module or pattern such as php*>...
+//	# plugins <caddy module id>...
+//	# weight <number>
+//
+// Imports are ordered by weight within a site, lower weights first. Plugins
+// providing non-standard directives used in the snippet are added to Plugins
+// automatically and the directives are recorded in Directives.
+type Metadata struct {
+	Version     string
+	Description string
+	Requires    []string
+	Conflicts   []string
+	Plugins     []string
+	Directives  []string
+	Weight      int
+}
+
+// readMetadata membaca metadata dari komentar di file modul
+func readMetadata(module string) (Metadata, error) {
+	file, err := caddyfile.Load(filepath.Join(moduleDir, module))
+	if err != nil {
+		return Metadata{Weight: defaultWeight}, err
+	}
+	return parseMetadata(module, file)
+}
+
+// parseMetadata membaca metadata dari komentar tingkat atas file modul
+func parseMetadata(module string, file *caddyfile.File) (Metadata, error) {
+	meta := Metadata{Weight: defaultWeight}
+	for _, node := range file.Nodes {
+		fields := strings.Fields(strings.TrimPrefix(node.Comment, "#"))
+		if len(node.Tokens) > 0 || len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "version":
+			meta.Version = fields[1]
+		case "description":
+			meta.Description = strings.Join(fields[1:], " ")
+		case "requires":
+			meta.Requires = append(meta.Requires, fields[1:]...)
+		case "conflicts":
+			meta.Conflicts = append(meta.Conflicts, fields[1:]...)
+		case "plugins":
+			meta.Plugins = append(meta.Plugins, fields[1:]...)
+		case "weight":
+			weight, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return meta, fmt.Errorf("weight tidak valid di modul %s: %s", module, fields[1])
+			}
+			meta.Weight = weight
+		}
+	}
+	requiredPlugins(&meta, file, module)
+	for _, pattern := range meta.Conflicts {
+		if _, err := filepath.Match(pattern, module); err != nil {
+			return meta, fmt.Errorf("pola conflicts tidak valid di modul %s: %s", module, pattern)
+		}
+	}
+	return meta, nil
+}
+
+// checkMetadata memeriksa requires, conflicts dan plugin modul terhadap modul
+// yang sudah diaktifkan untuk situs
+func checkMetadata(module string, meta Metadata, enabled []string) error {
+	for _, required := range meta.Requires {
+		if !contains(enabled, required) {
+			return fmt.Errorf("modul %s memerlukan modul %s, aktifkan %s terlebih dahulu", module, required, required)
+		}
+	}
+	for _, other := range enabled {
+		if other == module {
+			continue
+		}
+		if matchAny(meta.Conflicts, other) {
+			return fmt.Errorf("modul %s bertentangan dengan modul %s yang sudah diaktifkan", module, other)
+		}
+		// Konflik berlaku dua arah, misalnya php8.1 tidak dapat diaktifkan bersama spa
+		if otherMeta, err := readMetadata(other); err == nil && matchAny(otherMeta.Conflicts, module) {
+			return fmt.Errorf("modul %s bertentangan dengan modul %s yang sudah diaktifkan", module, other)
+		}
+	}
+
+	if len(meta.Plugins) > 0 {
+		installed, err := caddy.ListModules()
+		if err != nil {
+			return err
+		}
+		for _, plugin := range meta.Plugins {
+			if !contains(installed, plugin) {
+				return fmt.Errorf("modul %s memerlukan plugin Caddy %s yang tidak terpasang (lihat 'webpanel module build-caddy')", module, plugin)
+			}
+		}
+	}
+	return nil
+}
+
+// dependents mencari modul yang diaktifkan dan memerlukan modul
+func dependents(module string, enabled []string) []string {
+	users := []string{}
+	for _, other := range enabled {
+		if meta, err := readMetadata(other); err == nil && contains(meta.Requires, module) {
+			users = append(users, other)
+		}
+	}
+	return users
+}
+
+// matchAny memeriksa apakah nama modul cocok dengan salah satu pola
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -8,56 +8,42 @@ import (
 	"strings"
 
 	"github.com/doko89/webpanel/pkg/caddy"
+	"github.com/doko89/webpanel/pkg/caddyfile"
 )
 
-const (
-	moduleDir     = "/etc/caddy/module.d"
-	siteConfigDir = "/etc/caddy/sites.d"
-)
+const moduleDir = "/etc/caddy/module.d"
 
-// Enable enables a module for a specific domain
-func Enable(module, domain string) {
+// Enable enables a module for a specific domain. Parameters are given as
+// name=value and replace only those values of a module that is already enabled.
+func Enable(module, domain string, values []string) {
 	fmt.Printf("Enabling module %s for domain: %s\n", module, domain)
-	// Validasi modul
-	if !isModuleAvailable(module) {
-		fmt.Printf("Error: Modul tidak tersedia: %s\n", module)
+	// Validasi modul dan parameter
+	req, err := prepareEnable(module, values)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
 		return
 	}
 
-	// Validasi domain
-	configPath := filepath.Join(siteConfigDir, domain+".conf")
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		fmt.Printf("Error: Domain tidak ditemukan: %s\n", domain)
+	// Baca konfigurasi situs
+	configPath, file, site, err := loadDomain(domain)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
 		return
 	}
 
-	// Baca konfigurasi situs
-	content, err := ioutil.ReadFile(configPath)
+	changed, err := req.apply(site)
 	if err != nil {
-		fmt.Printf("Error: Tidak dapat membaca file konfigurasi: %s\n", err)
+		fmt.Printf("Error: %s\n", err)
 		return
 	}
-
-	// Periksa apakah modul sudah diaktifkan
-	if strings.Contains(string(content), "import "+module) {
+	if !changed {
 		fmt.Printf("Modul %s sudah diaktifkan untuk %s\n", module, domain)
 		return
 	}
 
-	// Tambahkan modul ke konfigurasi
-	lines := strings.Split(string(content), "\n")
-	for i, line := range lines {
-		if strings.Contains(line, domain+" {") {
-			// Tambahkan modul setelah baris pembuka
-			lines[i+1] = lines[i+1] + "\n\timport " + module
-			break
-		}
-	}
-
 	// Tulis kembali konfigurasi
-	newContent := strings.Join(lines, "\n")
-	if err := ioutil.WriteFile(configPath, []byte(newContent), 0644); err != nil {
-		fmt.Printf("Error: Tidak dapat menulis file konfigurasi: %s\n", err)
+	if err := caddy.ReplaceConfig(configPath, []byte(file.String())); err != nil {
+		fmt.Printf("Error: Perubahan dibatalkan: %s\n", err)
 		return
 	}
 
@@ -67,38 +53,36 @@ func Enable(module, domain string) {
 	}
 
 	fmt.Printf("Modul %s berhasil diaktifkan untuk %s\n", module, domain)
+	if len(req.params) > 0 {
+		if existing := findImport(site, module); existing != nil {
+			fmt.Printf("Parameter: %s\n", describeArgs(req.params, existing.Args()[1:]))
+		}
+	}
 }
 
 // Disable disables a module for a specific domain
 func Disable(module, domain string) {
 	fmt.Printf("Disabling module %s for domain: %s\n", module, domain)
-	// Validasi domain
-	configPath := filepath.Join(siteConfigDir, domain+".conf")
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		fmt.Printf("Error: Domain tidak ditemukan: %s\n", domain)
+	// Baca konfigurasi situs
+	configPath, file, site, err := loadDomain(domain)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
 		return
 	}
 
-	// Baca konfigurasi situs
-	content, err := ioutil.ReadFile(configPath)
+	changed, err := disableIn(site, module)
 	if err != nil {
-		fmt.Printf("Error: Tidak dapat membaca file konfigurasi: %s\n", err)
+		fmt.Printf("Error: %s\n", err)
 		return
 	}
-
-	// Periksa apakah modul diaktifkan
-	if !strings.Contains(string(content), "import "+module) {
+	if !changed {
 		fmt.Printf("Modul %s tidak diaktifkan untuk %s\n", module, domain)
 		return
 	}
 
-	// Hapus modul dari konfigurasi
-	newContent := strings.Replace(string(content), "\timport "+module, "", -1)
-	newContent = strings.Replace(newContent, "\n\n", "\n", -1) // Bersihkan baris kosong ganda
-
 	// Tulis kembali konfigurasi
-	if err := ioutil.WriteFile(configPath, []byte(newContent), 0644); err != nil {
-		fmt.Printf("Error: Tidak dapat menulis file konfigurasi: %s\n", err)
+	if err := caddy.ReplaceConfig(configPath, []byte(file.String())); err != nil {
+		fmt.Printf("Error: Perubahan dibatalkan: %s\n", err)
 		return
 	}
 
@@ -110,32 +94,108 @@ func Disable(module, domain string) {
 	fmt.Printf("Modul %s berhasil dinonaktifkan untuk %s\n", module, domain)
 }
 
+// enableRequest adalah import modul yang sudah divalidasi beserta metadatanya
+type enableRequest struct {
+	module    string
+	params    []Param
+	values    []string
+	args      []string
+	meta      Metadata
+	directive *caddyfile.Node
+}
+
+// prepareEnable memvalidasi modul dan parameternya sebelum situs diubah
+func prepareEnable(module string, values []string) (*enableRequest, error) {
+	if !IsAvailable(module) {
+		return nil, fmt.Errorf("modul tidak tersedia: %s", module)
+	}
+	params, err := readParams(module)
+	if err != nil {
+		return nil, err
+	}
+	args, err := buildArgs(module, params, values, nil)
+	if err != nil {
+		return nil, err
+	}
+	meta, err := readMetadata(module)
+	if err != nil {
+		return nil, err
+	}
+	return &enableRequest{
+		module:    module,
+		params:    params,
+		values:    values,
+		args:      args,
+		meta:      meta,
+		directive: caddyfile.New(append([]string{"import", module}, args...)...),
+	}, nil
+}
+
+// apply menambahkan import modul ke blok situs, mengembalikan false jika
+// modul sudah diaktifkan dan tidak ada yang berubah
+func (r *enableRequest) apply(site *caddyfile.Node) (bool, error) {
+	// Periksa apakah modul sudah diaktifkan, parameter baru menggantikan yang lama
+	if existing := findImport(site, r.module); existing != nil {
+		// Hanya parameter yang diberikan yang berubah; parameter lain tetap
+		// memakai nilai lama, dan import tanpa argumen dilengkapi nilai default
+		args, err := buildArgs(r.module, r.params, r.values, existing.Args()[1:])
+		if err != nil {
+			return false, err
+		}
+		tokens := caddyfile.New(append([]string{"import", r.module}, args...)...).Tokens
+		if strings.Join(existing.Tokens, " ") == strings.Join(tokens, " ") {
+			return false, nil
+		}
+		existing.Tokens = append([]string{}, tokens...)
+		return true, nil
+	}
+	if err := checkMetadata(r.module, r.meta, enabledModules(site)); err != nil {
+		return false, err
+	}
+	insertImport(site, &caddyfile.Node{Tokens: append([]string{}, r.directive.Tokens...)}, r.meta.Weight)
+	return true, nil
+}
+
+// disableIn menghapus import modul dari blok situs, mengembalikan false jika
+// modul tidak diaktifkan
+func disableIn(site *caddyfile.Node, module string) (bool, error) {
+	// Modul yang diperlukan modul lain tidak dapat dinonaktifkan
+	if users := dependents(module, enabledModules(site)); len(users) > 0 {
+		return false, fmt.Errorf("modul %s diperlukan oleh modul %s", module, strings.Join(users, ", "))
+	}
+
+	// Hapus hanya import dengan nama modul yang sama persis
+	removed := site.Remove(func(node *caddyfile.Node) bool {
+		return node.Name() == "import" && node.Arg(0) == module
+	})
+	return removed > 0, nil
+}
+
 // List displays all modules enabled for a domain
 func List(domain string) {
 	fmt.Printf("Listing modules for domain: %s\n", domain)
-	// Validasi domain
-	configPath := filepath.Join(siteConfigDir, domain+".conf")
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		fmt.Printf("Error: Domain tidak ditemukan: %s\n", domain)
-		return
-	}
-
 	// Baca konfigurasi situs
-	content, err := ioutil.ReadFile(configPath)
+	_, _, site, err := loadDomain(domain)
 	if err != nil {
-		fmt.Printf("Error: Tidak dapat membaca file konfigurasi: %s\n", err)
+		fmt.Printf("Error: %s\n", err)
 		return
 	}
 
-	// Cari modul yang diaktifkan
+	// Cari modul yang diaktifkan beserta nilai parameternya
 	modules := []string{}
-	lines := strings.Split(string(content), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "import ") {
-			moduleName := strings.TrimPrefix(line, "import ")
-			modules = append(modules, moduleName)
+	for _, node := range site.FindAll("import") {
+		args := node.Args()
+		if len(args) == 0 {
+			continue
 		}
+		description := args[0]
+		if params, err := readParams(args[0]); err == nil && len(params) > 0 {
+			description += " (" + describeArgs(params, args[1:]) + ")"
+			if len(args)-1 < len(params) {
+				description += fmt.Sprintf(", jalankan 'webpanel module enable %s %s' untuk mengisi nilai default", args[0], domain)
+			}
+		}
+		modules = append(modules, description)
 	}
 
 	// Tampilkan modul
@@ -166,16 +226,112 @@ func ListAvailable() {
 
 	fmt.Println("Modul yang tersedia:")
 	for _, file := range files {
-		if !file.IsDir() {
-			moduleName := strings.TrimSuffix(file.Name(), filepath.Ext(file.Name()))
+		// Lewati direktori dan file sementara
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+		moduleName := file.Name()
+		if err := validateName(moduleName); err != nil {
+			fmt.Printf("- %s (tidak dapat digunakan: %s)\n", moduleName, err)
+			continue
+		}
+		meta, err := readMetadata(moduleName)
+		if err != nil {
+			fmt.Printf("- %s (tidak dapat dibaca: %s)\n", moduleName, err)
+			continue
+		}
+		if meta.Description != "" {
+			fmt.Printf("- %s: %s\n", moduleName, meta.Description)
+		} else {
 			fmt.Println("-", moduleName)
 		}
+		if params, err := readParams(moduleName); err == nil && len(params) > 0 {
+			defaults := []string{}
+			for _, param := range params {
+				defaults = append(defaults, param.Name+"="+param.Default)
+			}
+			fmt.Printf("    parameter: %s\n", strings.Join(defaults, ", "))
+		}
+		if len(meta.Requires) > 0 {
+			fmt.Printf("    memerlukan: %s\n", strings.Join(meta.Requires, ", "))
+		}
+		if len(meta.Conflicts) > 0 {
+			fmt.Printf("    konflik: %s\n", strings.Join(meta.Conflicts, ", "))
+		}
+		if len(meta.Plugins) > 0 {
+			fmt.Printf("    plugin Caddy: %s\n", strings.Join(meta.Plugins, ", "))
+		}
+		if meta.Version != "" {
+			fmt.Printf("    versi: %s\n", meta.Version)
+		}
+		if meta.Weight != defaultWeight {
+			fmt.Printf("    urutan: %d\n", meta.Weight)
+		}
+	}
+}
+
+// IsAvailable reports whether a module exists in the module directory. The
+// module name is the file name without any extension stripping.
+func IsAvailable(moduleName string) bool {
+	if validateName(moduleName) != nil {
+		return false
+	}
+	info, err := os.Stat(filepath.Join(moduleDir, moduleName))
+	return err == nil && !info.IsDir()
+}
+
+// loadDomain membaca konfigurasi domain untuk semua jenis situs: file server,
+// PHP, proxy dan pengalihan
+func loadDomain(domain string) (string, *caddyfile.File, *caddyfile.Node, error) {
+	config, ok := caddy.FindSiteConfig(domain)
+	if !ok {
+		return "", nil, nil, fmt.Errorf("domain tidak ditemukan: %s", domain)
+	}
+	file, site, err := caddyfile.LoadSite(config.Path)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("tidak dapat membaca file konfigurasi: %w", err)
+	}
+	return config.Path, file, site, nil
+}
+
+// enabledModules mengembalikan nama modul yang diimpor oleh blok situs
+func enabledModules(site *caddyfile.Node) []string {
+	modules := []string{}
+	for _, node := range site.FindAll("import") {
+		if name := node.Arg(0); name != "" {
+			modules = append(modules, name)
+		}
+	}
+	return modules
+}
+
+// insertImport menambahkan import modul setelah direktif root dan import
+// modul lain dengan bobot yang sama atau lebih kecil
+func insertImport(site *caddyfile.Node, directive *caddyfile.Node, weight int) {
+	index := 0
+	for i, node := range site.Block {
+		switch node.Name() {
+		case "root":
+			index = i + 1
+		case "import":
+			meta, err := readMetadata(node.Arg(0))
+			if err != nil || meta.Weight <= weight {
+				index = i + 1
+			}
+		}
 	}
+	block := make([]*caddyfile.Node, 0, len(site.Block)+1)
+	block = append(block, site.Block[:index]...)
+	block = append(block, directive)
+	site.Block = append(block, site.Block[index:]...)
 }
 
-// isModuleAvailable memeriksa apakah modul tersedia
-func isModuleAvailable(moduleName string) bool {
-	modulePath := filepath.Join(moduleDir, moduleName)
-	_, err := os.Stat(modulePath)
-	return !os.IsNotExist(err)
+// findImport mencari direktif import untuk modul di blok situs
+func findImport(site *caddyfile.Node, module string) *caddyfile.Node {
+	for _, node := range site.FindAll("import") {
+		if node.Arg(0) == module {
+			return node
+		}
+	}
+	return nil
 }
@@ -0,0 +1,204 @@
+package module
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/doko89/webpanel/pkg/caddyfile"
+)
+
+// paramTypes adalah tipe parameter modul yang dapat divalidasi
+var paramTypes = []string{"int", "duration", "string", "cidrs"}
+
+// Param is a parameter declared in the header comments of a module file as
+//
+//	# param <name> <type> <default> [description]
+//
+// Parameters are passed to the snippet as import arguments in declaration
+// order, so the first one is {args[0]}. A cidrs parameter takes a comma
+// separated list, must be declared last and is used as {args[N:]}.
+type Param struct {
+	Name        string
+	Type        string
+	Default     string
+	Description string
+}
+
+// readParams membaca deklarasi parameter dari komentar di file modul
+func readParams(module string) ([]Param, error) {
+	file, err := caddyfile.Load(filepath.Join(moduleDir, module))
+	if err != nil {
+		return nil, err
+	}
+	return parseParams(module, file)
+}
+
+// parseParams membaca deklarasi parameter dari komentar tingkat atas file modul
+func parseParams(module string, file *caddyfile.File) ([]Param, error) {
+	params := []Param{}
+	for _, node := range file.Nodes {
+		fields := strings.Fields(strings.TrimPrefix(node.Comment, "#"))
+		if len(node.Tokens) > 0 || len(fields) == 0 || fields[0] != "param" {
+			continue
+		}
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("deklarasi parameter tidak lengkap di modul %s: %s", module, node.Comment)
+		}
+		param := Param{Name: fields[1], Type: fields[2], Default: fields[3], Description: strings.Join(fields[4:], " ")}
+		if !contains(paramTypes, param.Type) {
+			return nil, fmt.Errorf("tipe parameter %s tidak dikenal di modul %s (gunakan %s)", param.Type, module, strings.Join(paramTypes, ", "))
+		}
+		if len(params) > 0 && params[len(params)-1].Type == "cidrs" {
+			return nil, fmt.Errorf("parameter cidrs harus dideklarasikan terakhir di modul %s", module)
+		}
+		params = append(params, param)
+	}
+	return params, nil
+}
+
+// buildArgs menyusun argumen import dari nilai "nama=nilai". Parameter yang
+// tidak diberikan memakai nilainya di argumen current, atau nilai default jika
+// current tidak memuatnya.
+func buildArgs(module string, params []Param, values []string, current []string) ([]string, error) {
+	given := map[string]string{}
+	for _, value := range values {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("parameter harus berbentuk nama=nilai: %s", value)
+		}
+		if findParam(params, parts[0]) == nil {
+			return nil, fmt.Errorf("modul %s tidak memiliki parameter %s%s", module, parts[0], paramNames(params))
+		}
+		given[parts[0]] = parts[1]
+	}
+
+	existing := paramValues(params, completeArgs(params, current))
+	args := []string{}
+	for i, param := range params {
+		value, ok := given[param.Name]
+		if !ok {
+			value = existing[i]
+		}
+		if err := validateParam(param, value); err != nil {
+			return nil, err
+		}
+		if param.Type == "cidrs" {
+			args = append(args, strings.Split(value, ",")...)
+		} else {
+			args = append(args, value)
+		}
+	}
+	return args, nil
+}
+
+// validateParam memeriksa nilai parameter sesuai tipenya
+func validateParam(param Param, value string) error {
+	switch param.Type {
+	case "int":
+		if n, err := strconv.Atoi(value); err != nil || n < 1 {
+			return fmt.Errorf("parameter %s harus berupa bilangan bulat positif: %s", param.Name, value)
+		}
+	case "duration":
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			return fmt.Errorf("parameter %s harus berupa durasi, misalnya 10s atau 1m: %s", param.Name, value)
+		}
+	case "string":
+		if value == "" {
+			return fmt.Errorf("parameter %s tidak boleh kosong", param.Name)
+		}
+	case "cidrs":
+		for _, item := range strings.Split(value, ",") {
+			if net.ParseIP(item) == nil {
+				if _, _, err := net.ParseCIDR(item); err != nil {
+					return fmt.Errorf("parameter %s berisi alamat IP atau CIDR tidak valid: %s", param.Name, item)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// completeArgs melengkapi argumen import yang kurang dengan nilai default
+// parameter. Caddy tidak mengenal default, import tanpa argumen membiarkan
+// {args[N]} apa adanya di konfigurasi.
+func completeArgs(params []Param, args []string) []string {
+	complete := append([]string{}, args...)
+	for i := len(args); i < len(params); i++ {
+		if params[i].Type == "cidrs" {
+			complete = append(complete, strings.Split(params[i].Default, ",")...)
+		} else {
+			complete = append(complete, params[i].Default)
+		}
+	}
+	return complete
+}
+
+// paramValues mengembalikan nilai setiap parameter dari argumen import yang
+// lengkap; nilai cidrs digabung dengan koma
+func paramValues(params []Param, args []string) []string {
+	values := []string{}
+	for i, param := range params {
+		switch {
+		case i >= len(args):
+			values = append(values, param.Default)
+		case param.Type == "cidrs":
+			values = append(values, strings.Join(args[i:], ","))
+		default:
+			values = append(values, args[i])
+		}
+	}
+	return values
+}
+
+// describeArgs menampilkan argumen import sebagai daftar nama=nilai
+func describeArgs(params []Param, args []string) string {
+	parts := []string{}
+	for i, param := range params {
+		if i >= len(args) {
+			parts = append(parts, param.Name+" tidak diisi")
+			continue
+		}
+		value := args[i]
+		if param.Type == "cidrs" {
+			value = strings.Join(args[i:], ",")
+		}
+		parts = append(parts, param.Name+"="+value)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// findParam mencari parameter berdasarkan nama
+func findParam(params []Param, name string) *Param {
+	for i := range params {
+		if params[i].Name == name {
+			return &params[i]
+		}
+	}
+	return nil
+}
+
+// paramNames menampilkan daftar parameter yang tersedia untuk pesan error
+func paramNames(params []Param) string {
+	if len(params) == 0 {
+		return ""
+	}
+	names := []string{}
+	for _, param := range params {
+		names = append(names, param.Name)
+	}
+	return " (parameter yang tersedia: " + strings.Join(names, ", ") + ")"
+}
+
+// contains memeriksa apakah daftar berisi nilai
+func contains(items []string, value string) bool {
+	for _, item := range items {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,282 @@
+package module
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/doko89/webpanel/pkg/caddy"
+	"github.com/doko89/webpanel/pkg/caddyfile"
+)
+
+const caddyPackage = "github.com/caddyserver/caddy/v2"
+
+// plugin adalah plugin Caddy beserta paket Go yang menyediakannya
+type plugin struct {
+	id  string
+	pkg string
+}
+
+// pluginDirectives memetakan direktif non-standar ke plugin yang menyediakannya
+var pluginDirectives = map[string]plugin{
+	"rate_limit": {"http.handlers.rate_limit", "github.com/mholt/caddy-ratelimit"},
+	"cache":      {"http.handlers.cache", "github.com/caddyserver/cache-handler"},
+	"replace":    {"http.handlers.replace_response", "github.com/caddyserver/replace-response"},
+}
+
+// standardDirectives adalah direktif HTTP yang tersedia di Caddy standar
+var standardDirectives = []string{
+	"abort", "acme_server", "basic_auth", "basicauth", "bind", "copy_response",
+	"copy_response_headers", "encode", "error", "file_server", "forward_auth", "fs",
+	"handle", "handle_errors", "handle_path", "header", "import", "intercept", "invoke",
+	"log", "log_append", "log_skip", "map", "method", "metrics", "php_fastcgi", "push",
+	"redir", "request_body", "request_header", "respond", "reverse_proxy", "rewrite",
+	"root", "route", "skip_log", "templates", "tls", "tracing", "try_files", "uri", "vars",
+}
+
+// routeBlocks adalah direktif yang isinya juga berupa direktif
+var routeBlocks = []string{"handle", "handle_path", "handle_errors", "route"}
+
+// Plugins displays the Caddy plugins each module needs and whether the
+// installed Caddy binary provides them
+func Plugins() {
+	fmt.Println("Checking Caddy plugins required by modules:")
+	installed, err := caddy.ListModules()
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+
+	found := false
+	for _, name := range availableModules() {
+		meta, err := readMetadata(name)
+		if err != nil {
+			fmt.Printf("- %s: tidak dapat dibaca: %s\n", name, err)
+			continue
+		}
+		unknown := unknownDirectives(meta.Directives)
+		if len(meta.Plugins) == 0 && len(unknown) == 0 {
+			continue
+		}
+		found = true
+
+		statuses := []string{}
+		for _, id := range meta.Plugins {
+			status := "tidak terpasang"
+			if contains(installed, id) {
+				status = "terpasang"
+			}
+			statuses = append(statuses, id+" ("+status+")")
+		}
+		fmt.Printf("- %s: %s\n", name, valueOrDash(strings.Join(statuses, ", ")))
+		if len(meta.Directives) > 0 {
+			fmt.Printf("    direktif: %s\n", strings.Join(meta.Directives, ", "))
+		}
+		if len(unknown) > 0 && len(meta.Plugins) == 0 {
+			fmt.Printf("    Peringatan: direktif tidak dikenal, plugin-nya harus dideklarasikan dengan '# plugins': %s\n", strings.Join(unknown, ", "))
+		}
+	}
+	if !found {
+		fmt.Println("Semua modul hanya menggunakan direktif Caddy standar")
+	}
+}
+
+// BuildCaddy builds a Caddy binary that includes the plugins needed by the
+// available modules and the extra Go packages given. Dependencies are taken
+// from the local Go module cache unless online is set.
+func BuildCaddy(output string, extra []string, online bool) {
+	fmt.Printf("Building Caddy with module plugins to: %s\n", output)
+	if _, err := exec.LookPath("go"); err != nil {
+		fmt.Println("Error: Go tidak terpasang, diperlukan untuk membangun Caddy")
+		return
+	}
+
+	packages, unknown, err := pluginPackages()
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	if len(unknown) > 0 {
+		if len(extra) == 0 {
+			fmt.Printf("Error: Paket Go untuk plugin %s tidak diketahui, tambahkan dengan --with <paket>\n", strings.Join(unknown, ", "))
+			return
+		}
+		fmt.Printf("Peringatan: Paket Go untuk plugin %s diharapkan ada di --with\n", strings.Join(unknown, ", "))
+	}
+	for _, pkg := range extra {
+		if !contains(packages, pkg) {
+			packages = append(packages, pkg)
+		}
+	}
+	if len(packages) == 0 {
+		fmt.Println("Tidak ada plugin yang diperlukan, Caddy standar sudah cukup")
+		return
+	}
+	fmt.Printf("Plugin: %s\n", strings.Join(packages, ", "))
+
+	output, err = filepath.Abs(output)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	dir, err := ioutil.TempDir("", "webpanel-caddy-build-")
+	if err != nil {
+		fmt.Printf("Error: Tidak dapat membuat direktori sementara: %s\n", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte(buildMain(packages)), 0644); err != nil {
+		fmt.Printf("Error: Tidak dapat menulis sumber Caddy: %s\n", err)
+		return
+	}
+
+	// Gunakan versi Caddy yang sama dengan yang terpasang jika dapat dibaca
+	caddyModule := caddyPackage
+	if version := caddyVersion(); version != "" {
+		caddyModule += "@" + version
+	}
+
+	env := os.Environ()
+	if !online {
+		// Hanya gunakan cache modul Go lokal, tanpa mengunduh
+		env = append(env, "GOPROXY=off", "GOFLAGS=-mod=mod")
+	}
+	steps := [][]string{
+		{"go", "mod", "init", "caddy"},
+		append([]string{"go", "get", caddyModule}, packages...),
+		{"go", "mod", "tidy"},
+		{"go", "build", "-o", output, "."},
+	}
+	for _, step := range steps {
+		cmd := exec.Command(step[0], step[1:]...)
+		cmd.Dir = dir
+		cmd.Env = env
+		if out, err := cmd.CombinedOutput(); err != nil {
+			fmt.Printf("Error: %s gagal: %s\n%s", strings.Join(step, " "), err, string(out))
+			if !online {
+				fmt.Println("Catatan: Modul yang dibutuhkan harus ada di cache modul Go, atau gunakan --online")
+			}
+			return
+		}
+	}
+
+	fmt.Printf("Caddy berhasil dibangun: %s\n", output)
+	fmt.Println("Pasang dengan mempertahankan binary paket Debian:")
+	fmt.Println("  dpkg-divert --divert /usr/bin/caddy.default --rename /usr/bin/caddy")
+	fmt.Printf("  install -m 0755 %s /usr/bin/caddy\n", output)
+	fmt.Println("  systemctl restart caddy")
+}
+
+// requiredPlugins menambahkan plugin untuk direktif non-standar di snippet ke
+// metadata dan mencatat direktif tersebut
+func requiredPlugins(meta *Metadata, file *caddyfile.File, module string) {
+	snippet := file.Snippet(module)
+	if snippet == nil {
+		return
+	}
+	for _, directive := range snippetDirectives(snippet) {
+		if contains(standardDirectives, directive) || contains(meta.Directives, directive) {
+			continue
+		}
+		meta.Directives = append(meta.Directives, directive)
+		if p, ok := pluginDirectives[directive]; ok && !contains(meta.Plugins, p.id) {
+			meta.Plugins = append(meta.Plugins, p.id)
+		}
+	}
+}
+
+// snippetDirectives mengembalikan nama direktif di snippet, termasuk di dalam
+// blok handle dan route; definisi matcher dilewati
+func snippetDirectives(node *caddyfile.Node) []string {
+	directives := []string{}
+	for _, child := range node.Block {
+		name := child.Name()
+		if name == "" || strings.HasPrefix(name, "@") {
+			continue
+		}
+		directives = append(directives, name)
+		if child.Open && contains(routeBlocks, name) {
+			directives = append(directives, snippetDirectives(child)...)
+		}
+	}
+	return directives
+}
+
+// unknownDirectives mengembalikan direktif non-standar yang plugin-nya tidak diketahui
+func unknownDirectives(directives []string) []string {
+	unknown := []string{}
+	for _, directive := range directives {
+		if _, ok := pluginDirectives[directive]; !ok {
+			unknown = append(unknown, directive)
+		}
+	}
+	return unknown
+}
+
+// pluginPackages mengembalikan paket Go untuk semua plugin yang dibutuhkan
+// modul beserta ID plugin yang paketnya tidak diketahui
+func pluginPackages() ([]string, []string, error) {
+	packages, unknown := []string{}, []string{}
+	for _, name := range availableModules() {
+		meta, err := readMetadata(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, id := range meta.Plugins {
+			pkg := pluginPackage(id)
+			if pkg == "" {
+				if !contains(unknown, id) {
+					unknown = append(unknown, id)
+				}
+				continue
+			}
+			if !contains(packages, pkg) {
+				packages = append(packages, pkg)
+			}
+		}
+	}
+	sort.Strings(packages)
+	return packages, unknown, nil
+}
+
+// pluginPackage mencari paket Go yang menyediakan ID plugin Caddy
+func pluginPackage(id string) string {
+	for _, p := range pluginDirectives {
+		if p.id == id {
+			return p.pkg
+		}
+	}
+	return ""
+}
+
+// buildMain menyusun program Caddy yang mengimpor modul standar dan plugin
+func buildMain(packages []string) string {
+	var b strings.Builder
+	b.WriteString("package main\n\nimport (\n")
+	b.WriteString("\tcaddycmd \"" + caddyPackage + "/cmd\"\n")
+	b.WriteString("\t_ \"" + caddyPackage + "/modules/standard\"\n")
+	for _, pkg := range packages {
+		// Paket boleh diberi versi untuk go get, misalnya paket@v1.2.3
+		b.WriteString("\t_ \"" + strings.SplitN(pkg, "@", 2)[0] + "\"\n")
+	}
+	b.WriteString(")\n\nfunc main() {\n\tcaddycmd.Main()\n}\n")
+	return b.String()
+}
+
+// caddyVersion membaca versi Caddy yang terpasang, misalnya v2.7.6
+func caddyVersion() string {
+	output, err := exec.Command("caddy", "version").Output()
+	if err != nil {
+		return ""
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "v2.") {
+		return ""
+	}
+	return fields[0]
+}
@@ -0,0 +1,222 @@
+package module
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/doko89/webpanel/pkg/caddy"
+	"github.com/doko89/webpanel/pkg/caddyfile"
+)
+
+// siteImports adalah modul yang diimpor oleh satu situs beserta argumennya
+type siteImports struct {
+	config  caddy.SiteConfig
+	imports map[string][]string
+}
+
+// Show displays the content and metadata of a module and the sites importing it
+func Show(name string) {
+	fmt.Printf("Showing module: %s\n", name)
+	if !IsAvailable(name) {
+		fmt.Printf("Error: Modul tidak tersedia: %s\n", name)
+		return
+	}
+	modulePath := filepath.Join(moduleDir, name)
+	content, err := ioutil.ReadFile(modulePath)
+	if err != nil {
+		fmt.Printf("Error: Tidak dapat membaca modul: %s\n", err)
+		return
+	}
+	meta, err := readMetadata(name)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	params, err := readParams(name)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+
+	fmt.Printf("Modul      : %s\n", name)
+	fmt.Printf("File       : %s\n", modulePath)
+	fmt.Printf("Versi      : %s\n", valueOrDash(meta.Version))
+	fmt.Printf("Deskripsi  : %s\n", valueOrDash(meta.Description))
+	fmt.Printf("Memerlukan : %s\n", valueOrDash(strings.Join(meta.Requires, ", ")))
+	fmt.Printf("Konflik    : %s\n", valueOrDash(strings.Join(meta.Conflicts, ", ")))
+	fmt.Printf("Plugin     : %s\n", valueOrDash(strings.Join(meta.Plugins, ", ")))
+	fmt.Printf("Direktif   : %s\n", valueOrDash(strings.Join(meta.Directives, ", ")))
+	fmt.Printf("Urutan     : %d\n", meta.Weight)
+	for _, param := range params {
+		fmt.Printf("Parameter  : %s (%s, default %s) %s\n", param.Name, param.Type, param.Default, param.Description)
+	}
+
+	sites, err := scanImports()
+	if err != nil {
+		fmt.Printf("Peringatan: Tidak dapat membaca konfigurasi situs: %s\n", err)
+	}
+	users := 0
+	fmt.Println("\nDigunakan oleh:")
+	for _, site := range sites {
+		args, ok := site.imports[name]
+		if !ok {
+			continue
+		}
+		users++
+		line := fmt.Sprintf("- %s (%s)", site.config.Domain, site.config.Kind)
+		if len(params) > 0 {
+			line += " " + describeArgs(params, args)
+		}
+		fmt.Println(line)
+	}
+	if users == 0 {
+		fmt.Println("- (tidak ada)")
+	}
+
+	fmt.Println("\nIsi modul:")
+	fmt.Print(string(content))
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		fmt.Println()
+	}
+}
+
+// Usage prints a matrix of sites and the modules they import
+func Usage() {
+	fmt.Println("Showing module usage:")
+	sites, err := scanImports()
+	if err != nil {
+		fmt.Printf("Error: Tidak dapat membaca konfigurasi situs: %s\n", err)
+		return
+	}
+	if len(sites) == 0 {
+		fmt.Println("Tidak ada situs yang dikonfigurasi")
+		return
+	}
+
+	// Kolom berisi modul yang tersedia dan modul yang diimpor tetapi tidak ada
+	modules := availableModules()
+	missing := []string{}
+	for _, site := range sites {
+		for name := range site.imports {
+			if !contains(modules, name) && !contains(missing, name) {
+				missing = append(missing, name)
+			}
+		}
+	}
+	sort.Strings(missing)
+	modules = append(modules, missing...)
+	if len(modules) == 0 {
+		fmt.Println("Tidak ada modul yang tersedia")
+		return
+	}
+
+	width := len("SITUS")
+	for _, site := range sites {
+		if len(site.config.Domain) > width {
+			width = len(site.config.Domain)
+		}
+	}
+	header := fmt.Sprintf("%-*s  %-8s", width, "SITUS", "JENIS")
+	for _, name := range modules {
+		header += "  " + name
+	}
+	fmt.Println(header)
+
+	totals := make([]int, len(modules))
+	for _, site := range sites {
+		row := fmt.Sprintf("%-*s  %-8s", width, site.config.Domain, site.config.Kind)
+		for i, name := range modules {
+			mark := "."
+			if _, ok := site.imports[name]; ok {
+				mark = "x"
+				totals[i]++
+			}
+			row += "  " + fmt.Sprintf("%-*s", len(name), mark)
+		}
+		fmt.Println(strings.TrimRight(row, " "))
+	}
+
+	row := fmt.Sprintf("%-*s  %-8s", width, "TOTAL", "")
+	for i, name := range modules {
+		row += "  " + fmt.Sprintf("%-*d", len(name), totals[i])
+	}
+	fmt.Println(strings.TrimRight(row, " "))
+	if len(missing) > 0 {
+		fmt.Printf("Peringatan: Modul diimpor tetapi tidak tersedia: %s\n", strings.Join(missing, ", "))
+	}
+}
+
+// moduleUsers mencari domain yang mengimpor modul di konfigurasinya
+func moduleUsers(name string) []string {
+	users := []string{}
+	sites, _ := scanImports()
+	for _, site := range sites {
+		if _, ok := site.imports[name]; ok {
+			users = append(users, site.config.Domain)
+		}
+	}
+	return users
+}
+
+// scanImports membaca modul yang diimpor oleh setiap situs dari konfigurasi
+// yang sudah di-parse, termasuk import di dalam blok handle
+func scanImports() ([]siteImports, error) {
+	configs, err := caddy.SiteConfigs()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	sites := []siteImports{}
+	for _, config := range configs {
+		_, site, err := caddyfile.LoadSite(config.Path)
+		if err != nil {
+			fmt.Printf("Peringatan: %s dilewati: %s\n", config.Path, err)
+			continue
+		}
+		imports := map[string][]string{}
+		collectImports(site, imports)
+		sites = append(sites, siteImports{config: config, imports: imports})
+	}
+	return sites, nil
+}
+
+// collectImports mengumpulkan import modul dari blok dan blok di dalamnya
+func collectImports(node *caddyfile.Node, imports map[string][]string) {
+	for _, child := range node.Block {
+		if child.Name() == "import" && child.Arg(0) != "" {
+			imports[child.Arg(0)] = child.Args()[1:]
+		}
+		if child.Open {
+			collectImports(child, imports)
+		}
+	}
+}
+
+// availableModules mengembalikan nama modul yang valid di direktori modul
+func availableModules() []string {
+	modules := []string{}
+	files, err := ioutil.ReadDir(moduleDir)
+	if err != nil {
+		return modules
+	}
+	for _, file := range files {
+		if !file.IsDir() && validateName(file.Name()) == nil {
+			modules = append(modules, file.Name())
+		}
+	}
+	return modules
+}
+
+// valueOrDash mengembalikan "-" untuk nilai kosong
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
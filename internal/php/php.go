@@ -52,10 +52,12 @@ func InstallModule(module string) {
 
 // createPhpModule membuat modul Caddy untuk PHP
 func createPhpModule(version string) {
-	moduleContent := fmt.Sprintf(`(php%s) {
+	moduleContent := fmt.Sprintf(`# description Menjalankan PHP %s melalui php-fpm
+# weight 90
+(php%s) {
 	php_fastcgi unix//run/php/php%s-fpm.sock
 }
-`, version, version)
+`, version, version, version)
 
 	modulePath := filepath.Join(moduleDir, fmt.Sprintf("php%s", version))
 	if err := ioutil.WriteFile(modulePath, []byte(moduleContent), 0644); err != nil {
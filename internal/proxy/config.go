@@ -0,0 +1,414 @@
+package proxy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/doko89/webpanel/pkg/caddyfile"
+)
+
+// lbPolicies adalah kebijakan load balancing yang didukung
+var lbPolicies = []string{"round_robin", "least_conn", "ip_hash", "first", "random"}
+
+var sizePattern = regexp.MustCompile(`(?i)^\d+(\.\d+)?([KMGT]i?B|B)?$`)
+
+// Options lists the options accepted by 'proxy set'
+var Options = []string{
+	"lb", "health-uri", "health-interval", "host-header", "header-up", "header-down",
+	"dial-timeout", "read-timeout", "write-timeout", "buffering",
+	"tls-skip-verify", "max-body-size",
+}
+
+// Config describes the reverse_proxy directive of a proxy site
+type Config struct {
+	Domain         string
+	Upstreams      []string
+	LBPolicy       string
+	HealthURI      string
+	HealthInterval string
+	HostHeader     string
+	HeaderUp       []string // "Nama nilai", atau "-Nama" untuk menghapus header
+	HeaderDown     []string
+	DialTimeout    string
+	ReadTimeout    string
+	WriteTimeout   string
+	NoBuffering    bool
+	TLSSkipVerify  bool
+	MaxBodySize    string
+	Transport      string   // transport yang sudah ada di konfigurasi, misalnya http atau fastcgi
+	Services       []string // layanan yang menjadi target, dicatat sebagai komentar service:<nama>
+}
+
+// Set changes one option of the proxy. An empty value or "off" resets it.
+func (c *Config) Set(option, value string) error {
+	off := value == "" || value == "off"
+	switch option {
+	case "lb":
+		c.LBPolicy = resetIf(off, value)
+	case "health-uri":
+		c.HealthURI = resetIf(off, value)
+	case "health-interval":
+		c.HealthInterval = resetIf(off, value)
+	case "host-header":
+		c.HostHeader = resetIf(off, value)
+	case "header-up":
+		headers, err := setHeader(c.HeaderUp, value)
+		if err != nil {
+			return err
+		}
+		c.HeaderUp = headers
+	case "header-down":
+		headers, err := setHeader(c.HeaderDown, value)
+		if err != nil {
+			return err
+		}
+		c.HeaderDown = headers
+	case "trust-forwarded":
+		// Caddy hanya mengenal trusted_proxies sebagai opsi global server, bukan per reverse_proxy
+		return fmt.Errorf("header X-Forwarded-* dipercaya untuk semua situs, atur 'servers { trusted_proxies static <rentang> }' di blok opsi global /etc/caddy/Caddyfile")
+	case "dial-timeout":
+		c.DialTimeout = resetIf(off, value)
+	case "read-timeout":
+		c.ReadTimeout = resetIf(off, value)
+	case "write-timeout":
+		c.WriteTimeout = resetIf(off, value)
+	case "buffering":
+		enabled, err := parseSwitch(value)
+		if err != nil {
+			return err
+		}
+		c.NoBuffering = !enabled
+	case "tls-skip-verify":
+		enabled, err := parseSwitch(value)
+		if err != nil {
+			return err
+		}
+		c.TLSSkipVerify = enabled
+	case "max-body-size":
+		c.MaxBodySize = resetIf(off, value)
+	default:
+		return fmt.Errorf("opsi tidak dikenal: %s (opsi yang tersedia: %s)", option, strings.Join(Options, ", "))
+	}
+	return nil
+}
+
+// Validate checks the upstreams and proxy options
+func (c Config) Validate() error {
+	if !isValidDomain(c.Domain) {
+		return fmt.Errorf("domain tidak valid: %s", c.Domain)
+	}
+	if len(c.Upstreams) == 0 {
+		return fmt.Errorf("minimal satu target diperlukan")
+	}
+	seen := map[string]bool{}
+	for _, upstream := range c.Upstreams {
+		if _, err := parseTarget(upstream); err != nil {
+			return err
+		}
+		if seen[upstream] {
+			return fmt.Errorf("target duplikat: %s", upstream)
+		}
+		seen[upstream] = true
+	}
+	if c.LBPolicy != "" && !contains(lbPolicies, c.LBPolicy) {
+		return fmt.Errorf("kebijakan load balancing tidak valid: %s (harus salah satu dari %s)", c.LBPolicy, strings.Join(lbPolicies, ", "))
+	}
+	if c.HealthURI != "" && !strings.HasPrefix(c.HealthURI, "/") {
+		return fmt.Errorf("health URI harus diawali dengan /: %s", c.HealthURI)
+	}
+	if c.HealthInterval != "" && c.HealthURI == "" {
+		return fmt.Errorf("health interval memerlukan health URI")
+	}
+
+	durations := map[string]string{
+		"health interval": c.HealthInterval,
+		"dial timeout":    c.DialTimeout,
+		"read timeout":    c.ReadTimeout,
+		"write timeout":   c.WriteTimeout,
+	}
+	for name, value := range durations {
+		if value == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(value); err != nil {
+			return fmt.Errorf("%s tidak valid: %s", name, value)
+		}
+	}
+
+	if c.TLSSkipVerify && c.Transport != "" && c.Transport != "http" {
+		return fmt.Errorf("tls-skip-verify hanya berlaku untuk transport http, bukan %s", c.Transport)
+	}
+	if c.MaxBodySize != "" && !sizePattern.MatchString(c.MaxBodySize) {
+		return fmt.Errorf("ukuran maksimum body tidak valid: %s (contoh: 10MB)", c.MaxBodySize)
+	}
+	if strings.ContainsAny(c.HostHeader, " \t\"") {
+		return fmt.Errorf("host header tidak valid: %s", c.HostHeader)
+	}
+	return nil
+}
+
+// directive menyusun direktif reverse_proxy beserta bloknya
+func (c Config) directive() *caddyfile.Node {
+	node := caddyfile.New("reverse_proxy")
+	c.applyDirective(node)
+	return node
+}
+
+// render menyusun konfigurasi lengkap untuk proxy baru
+func (c Config) render() string {
+	site := &caddyfile.Node{Tokens: []string{c.Domain}, Open: true}
+	c.apply(site)
+	return (&caddyfile.File{Nodes: []*caddyfile.Node{site}}).String()
+}
+
+// apply menulis pengaturan ke direktif reverse_proxy dan request_body di blok
+// situs. Subdirektif yang sudah ada diubah di tempatnya dan subdirektif yang
+// tidak dikelola webpanel, misalnya lb_try_duration atau keepalive, dibiarkan.
+func (c Config) apply(site *caddyfile.Node) {
+	c.applyRequestBody(site)
+	node := site.Find("reverse_proxy")
+	if node == nil {
+		site.Set("reverse_proxy", c.directive())
+		return
+	}
+	c.applyDirective(node)
+}
+
+// applyDirective mengubah target dan subdirektif yang dikelola pada node reverse_proxy
+func (c Config) applyDirective(node *caddyfile.Node) {
+	// Semua target ditulis di baris reverse_proxy, termasuk yang sebelumnya di "to"
+	node.Tokens = caddyfile.New(append([]string{"reverse_proxy"}, c.Upstreams...)...).Tokens
+	node.Remove(func(child *caddyfile.Node) bool { return child.Name() == "to" })
+	node.Comment = withServices(node.Comment, c.Services)
+
+	setOption(node, "lb_policy", c.LBPolicy)
+	setOption(node, "health_uri", c.HealthURI)
+	setOption(node, "health_interval", c.HealthInterval)
+
+	headerUp := []string{}
+	if c.HostHeader != "" {
+		host := c.HostHeader
+		if host == "upstream" {
+			host = "{upstream_hostport}"
+		}
+		headerUp = append(headerUp, "Host "+host)
+	}
+	setHeaders(node, "header_up", append(headerUp, c.HeaderUp...))
+	// Caddy hanya mempercayai X-Forwarded-* yang datang dari proxy tepercaya
+	setHeaders(node, "header_down", c.HeaderDown)
+
+	// flush_interval selain -1 tidak dikelola dan dibiarkan
+	if c.NoBuffering {
+		setOption(node, "flush_interval", "-1")
+	} else if flush := node.Find("flush_interval"); flush != nil && flush.Arg(0) == "-1" {
+		setOption(node, "flush_interval")
+	}
+
+	c.applyTransport(node)
+	node.Open = len(node.Block) > 0
+}
+
+// applyTransport mengubah opsi transport; blok transport http baru hanya
+// ditulis jika ada opsi yang diatur
+func (c Config) applyTransport(node *caddyfile.Node) {
+	transport := node.Find("transport")
+	if transport == nil {
+		if c.DialTimeout == "" && c.ReadTimeout == "" && c.WriteTimeout == "" && !c.TLSSkipVerify {
+			return
+		}
+		transport = caddyfile.New("transport", "http")
+		node.Add(transport)
+	}
+
+	setOption(transport, "dial_timeout", c.DialTimeout)
+	setOption(transport, "read_timeout", c.ReadTimeout)
+	setOption(transport, "write_timeout", c.WriteTimeout)
+	if c.TLSSkipVerify {
+		if transport.Find("tls") == nil {
+			transport.Add(caddyfile.New("tls"))
+		}
+		if transport.Find("tls_insecure_skip_verify") == nil {
+			transport.Add(caddyfile.New("tls_insecure_skip_verify"))
+		}
+	} else {
+		transport.Remove(func(child *caddyfile.Node) bool { return child.Name() == "tls_insecure_skip_verify" })
+	}
+
+	transport.Open = len(transport.Block) > 0
+	if !transport.Open && len(transport.Tokens) == 2 && transport.Arg(0) == "http" {
+		node.Remove(func(child *caddyfile.Node) bool { return child == transport })
+	}
+}
+
+// applyRequestBody mengubah max_size di direktif request_body dan menghapus
+// direktif itu jika tidak berisi pengaturan lain
+func (c Config) applyRequestBody(site *caddyfile.Node) {
+	body := site.Find("request_body")
+	if body == nil {
+		if c.MaxBodySize != "" {
+			site.Set("request_body", caddyfile.New("request_body").Add(caddyfile.New("max_size", c.MaxBodySize)))
+		}
+		return
+	}
+	setOption(body, "max_size", c.MaxBodySize)
+	if len(body.Block) == 0 {
+		site.Remove(func(child *caddyfile.Node) bool { return child == body })
+	}
+}
+
+// setOption mengganti subdirektif name di tempatnya, menambahkannya di akhir
+// blok jika belum ada, atau menghapusnya jika tidak ada argumen yang terisi
+func setOption(parent *caddyfile.Node, name string, args ...string) {
+	if len(args) == 0 || (len(args) == 1 && args[0] == "") {
+		parent.Remove(func(child *caddyfile.Node) bool { return child.Name() == name })
+		return
+	}
+	node := caddyfile.New(append([]string{name}, args...)...)
+	if existing := parent.Find(name); existing != nil {
+		existing.Tokens = node.Tokens
+		return
+	}
+	parent.Add(node)
+}
+
+// setHeaders mengganti semua direktif header_up atau header_down dengan
+// daftar "Nama nilai"; baris yang tidak berubah dipertahankan apa adanya
+func setHeaders(parent *caddyfile.Node, name string, headers []string) {
+	existing := map[string]*caddyfile.Node{}
+	for _, node := range parent.FindAll(name) {
+		existing[strings.Join(node.Args(), " ")] = node
+	}
+	nodes := []*caddyfile.Node{}
+	for _, header := range headers {
+		if node, ok := existing[header]; ok {
+			nodes = append(nodes, node)
+			continue
+		}
+		nodes = append(nodes, caddyfile.New(append([]string{name}, strings.SplitN(header, " ", 2)...)...))
+	}
+
+	if len(existing) == 0 {
+		if len(nodes) > 0 {
+			parent.Add(nodes...)
+		}
+		return
+	}
+	parent.Set(name, nodes...)
+}
+
+// parseConfig membaca pengaturan reverse_proxy dari blok situs proxy
+func parseConfig(domain string, site *caddyfile.Node) Config {
+	c := Config{Domain: domain}
+	if site == nil {
+		return c
+	}
+
+	if body := site.Find("request_body"); body != nil {
+		if maxSize := body.Find("max_size"); maxSize != nil {
+			c.MaxBodySize = maxSize.Arg(0)
+		}
+	}
+
+	node := site.Find("reverse_proxy")
+	if node == nil {
+		return c
+	}
+	c.Upstreams = node.Args()
+	c.Services = commentServices(node.Comment)
+
+	for _, option := range node.Block {
+		args := option.Args()
+		switch option.Name() {
+		case "to":
+			c.Upstreams = append(c.Upstreams, args...)
+		case "lb_policy":
+			c.LBPolicy = option.Arg(0)
+		case "health_uri":
+			c.HealthURI = option.Arg(0)
+		case "health_interval":
+			c.HealthInterval = option.Arg(0)
+		case "header_up":
+			switch {
+			case len(args) == 2 && args[0] == "Host":
+				c.HostHeader = args[1]
+				if c.HostHeader == "{upstream_hostport}" {
+					c.HostHeader = "upstream"
+				}
+			default:
+				c.HeaderUp = append(c.HeaderUp, strings.Join(args, " "))
+			}
+		case "header_down":
+			c.HeaderDown = append(c.HeaderDown, strings.Join(args, " "))
+		case "flush_interval":
+			c.NoBuffering = option.Arg(0) == "-1"
+		case "transport":
+			c.Transport = option.Arg(0)
+			for _, transport := range option.Block {
+				switch transport.Name() {
+				case "dial_timeout":
+					c.DialTimeout = transport.Arg(0)
+				case "read_timeout":
+					c.ReadTimeout = transport.Arg(0)
+				case "write_timeout":
+					c.WriteTimeout = transport.Arg(0)
+				case "tls_insecure_skip_verify":
+					c.TLSSkipVerify = true
+				}
+			}
+		}
+	}
+	return c
+}
+
+// setHeader mengganti atau menambahkan header "Nama nilai"; hanya "Nama" berarti menghapusnya dari daftar
+func setHeader(headers []string, value string) ([]string, error) {
+	value = strings.TrimSpace(value)
+	parts := strings.SplitN(value, " ", 2)
+	name := parts[0]
+	if name == "" || strings.ContainsAny(name, "\"{}") {
+		return nil, fmt.Errorf("nama header tidak valid: %s", value)
+	}
+
+	result := []string{}
+	for _, header := range headers {
+		if strings.SplitN(header, " ", 2)[0] != name {
+			result = append(result, header)
+		}
+	}
+	// "-Nama" menghapus header di upstream dan tidak memerlukan nilai
+	if len(parts) == 2 || strings.HasPrefix(name, "-") {
+		result = append(result, value)
+	}
+	return result, nil
+}
+
+// parseSwitch mengurai nilai on/off
+func parseSwitch(value string) (bool, error) {
+	switch value {
+	case "on", "true", "yes":
+		return true, nil
+	case "off", "false", "no":
+		return false, nil
+	}
+	return false, fmt.Errorf("nilai harus on atau off: %s", value)
+}
+
+// resetIf mengembalikan string kosong jika opsi dinonaktifkan
+func resetIf(off bool, value string) string {
+	if off {
+		return ""
+	}
+	return value
+}
+
+// contains memeriksa apakah slice berisi nilai tertentu
+func contains(slice []string, value string) bool {
+	for _, item := range slice {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
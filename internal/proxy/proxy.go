@@ -8,35 +8,234 @@ import (
 	"strings"
 
 	"github.com/doko89/webpanel/pkg/caddy"
+	"github.com/doko89/webpanel/pkg/caddyfile"
 )
 
 const (
 	siteConfigDir = "/etc/caddy/sites.d"
 )
 
-// Add creates a new proxy with the given domain and target
-func Add(domain, target string) {
-	fmt.Printf("Adding proxy for domain: %s to target: %s\n", domain, target)
-	// Validasi domain dan target
-	if !isValidDomain(domain) {
-		fmt.Printf("Error: Domain tidak valid: %s\n", domain)
+// Add creates a new proxy for a domain with one or more upstream targets,
+// checking that every target is reachable unless check is false
+func Add(cfg Config, check bool) {
+	fmt.Printf("Adding proxy for domain: %s to target: %s\n", cfg.Domain, strings.Join(cfg.Upstreams, ", "))
+	upstreams, services, err := resolveTargets(cfg.Upstreams)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	cfg.Upstreams, cfg.Services = upstreams, services
+
+	// Validasi domain, target dan opsi load balancing
+	if err := cfg.Validate(); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	if check {
+		for _, upstream := range cfg.Upstreams {
+			if err := checkTarget(upstream); err != nil {
+				fmt.Printf("Error: %s\n", err)
+				return
+			}
+		}
+	}
+
+	if err := Create(cfg); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+
+	// Muat ulang Caddy
+	if err := caddy.Reload(); err != nil {
+		fmt.Printf("Peringatan: Tidak dapat memuat ulang Caddy: %s\n", err)
+	}
+
+	fmt.Printf("Situs proxy %s -> %s berhasil dibuat\n", cfg.Domain, strings.Join(cfg.Upstreams, ", "))
+}
+
+// Create writes and validates the configuration of a new proxy without
+// reloading Caddy. An invalid configuration is removed again.
+func Create(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	// Konfigurasi situs, proxy atau pengalihan yang sudah ada tidak ditimpa
+	if existing, ok := caddy.FindSiteConfig(cfg.Domain); ok {
+		return fmt.Errorf("konfigurasi untuk %s sudah ada di %s", cfg.Domain, existing.Path)
+	}
+
+	// Tulis secara atomik dan validasi, file baru dihapus jika tidak valid
+	configPath := filepath.Join(siteConfigDir, caddy.ConfigFileName(cfg.Domain, caddy.KindProxy))
+	if err := caddy.ReplaceConfig(configPath, []byte(cfg.render())); err != nil {
+		return fmt.Errorf("konfigurasi proxy tidak valid: %w", err)
+	}
+	return nil
+}
+
+// AddUpstream adds an upstream target to an existing proxy
+func AddUpstream(domain, target string, check bool) {
+	fmt.Printf("Adding upstream %s to proxy: %s\n", target, domain)
+	target, service, err := resolveTarget(target)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	updateConfig(domain, func(cfg *Config) error {
+		if contains(cfg.Upstreams, target) {
+			return fmt.Errorf("upstream %s sudah ada", target)
+		}
+		if check {
+			if err := checkTarget(target); err != nil {
+				return err
+			}
+		}
+		cfg.Upstreams = append(cfg.Upstreams, target)
+		if service != "" {
+			cfg.Services = append(cfg.Services, service)
+		}
+		return nil
+	})
+}
+
+// RemoveUpstream removes an upstream target from an existing proxy
+func RemoveUpstream(domain, target string) {
+	fmt.Printf("Removing upstream %s from proxy: %s\n", target, domain)
+	target, service, err := resolveTarget(target)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	updateConfig(domain, func(cfg *Config) error {
+		if !contains(cfg.Upstreams, target) {
+			return fmt.Errorf("upstream %s tidak ditemukan", target)
+		}
+		if len(cfg.Upstreams) == 1 {
+			return fmt.Errorf("tidak dapat menghapus upstream terakhir, gunakan 'proxy remove'")
+		}
+		upstreams := []string{}
+		for _, upstream := range cfg.Upstreams {
+			if upstream != target {
+				upstreams = append(upstreams, upstream)
+			}
+		}
+		cfg.Upstreams = upstreams
+		if service != "" {
+			cfg.Services = removeName(cfg.Services, service)
+		}
+		return nil
+	})
+}
+
+// Option is a named proxy option as accepted by Config.Set
+type Option struct {
+	Name  string
+	Value string
+}
+
+// Update replaces the targets and/or options of an existing proxy in place
+func Update(domain string, targets []string, options []Option, check bool) {
+	fmt.Printf("Updating proxy for domain: %s\n", domain)
+	if len(targets) == 0 && len(options) == 0 {
+		fmt.Println("Error: Tidak ada perubahan, gunakan --target atau opsi lain")
 		return
 	}
+	targets, services, err := resolveTargets(targets)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	updateConfig(domain, func(cfg *Config) error {
+		if len(targets) > 0 {
+			// Hanya target baru yang diperiksa koneksinya
+			for _, target := range targets {
+				if check && !contains(cfg.Upstreams, target) {
+					if err := checkTarget(target); err != nil {
+						return err
+					}
+				}
+			}
+			cfg.Upstreams, cfg.Services = targets, services
+		}
+		for _, option := range options {
+			if err := cfg.Set(option.Name, option.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
 
-	if !isValidTarget(target) {
-		fmt.Printf("Error: Target tidak valid: %s\n", target)
+// SetOption changes one option of an existing proxy
+func SetOption(domain, option, value string) {
+	fmt.Printf("Setting proxy option %s for domain: %s\n", option, domain)
+	updateConfig(domain, func(cfg *Config) error {
+		return cfg.Set(option, value)
+	})
+}
+
+// Info displays the upstreams and options of a proxy
+func Info(domain string) {
+	fmt.Printf("Proxy information for domain: %s\n", domain)
+	configPath := filepath.Join(siteConfigDir, "proxy."+domain+".conf")
+	_, site, err := caddyfile.LoadSite(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			fmt.Printf("Error: Proxy tidak ditemukan: %s\n", domain)
+		} else {
+			fmt.Printf("Error: Tidak dapat membaca file konfigurasi: %s\n", err)
+		}
 		return
 	}
 
-	// Buat file konfigurasi Caddy
-	configContent := fmt.Sprintf(`%s {
-	reverse_proxy %s
+	cfg := parseConfig(domain, site)
+	fmt.Printf("Domain          : %s\n", domain)
+	fmt.Printf("Konfigurasi     : %s\n", configPath)
+	fmt.Printf("Upstream        : %s\n", valueOrDash(strings.Join(cfg.Upstreams, ", ")))
+	fmt.Printf("Load balancing  : %s\n", valueOrDash(cfg.LBPolicy))
+	fmt.Printf("Health check    : %s\n", valueOrDash(strings.TrimSpace(cfg.HealthURI+" "+cfg.HealthInterval)))
+	fmt.Printf("Host header     : %s\n", valueOrDash(cfg.HostHeader))
+	fmt.Printf("Dial timeout    : %s\n", valueOrDash(cfg.DialTimeout))
+	fmt.Printf("Read timeout    : %s\n", valueOrDash(cfg.ReadTimeout))
+	fmt.Printf("Write timeout   : %s\n", valueOrDash(cfg.WriteTimeout))
+	fmt.Printf("Buffering       : %s\n", onOff(!cfg.NoBuffering))
+	fmt.Printf("TLS skip verify : %s\n", onOff(cfg.TLSSkipVerify))
+	fmt.Printf("Max body size   : %s\n", valueOrDash(cfg.MaxBodySize))
+	fmt.Printf("Layanan         : %s\n", valueOrDash(strings.Join(cfg.Services, ", ")))
+	for _, header := range cfg.HeaderUp {
+		fmt.Printf("Header up       : %s\n", header)
+	}
+	for _, header := range cfg.HeaderDown {
+		fmt.Printf("Header down     : %s\n", header)
+	}
 }
-`, domain, target)
 
+// updateConfig membaca konfigurasi proxy, mengubahnya dan menulisnya kembali
+func updateConfig(domain string, change func(cfg *Config) error) {
 	configPath := filepath.Join(siteConfigDir, "proxy."+domain+".conf")
-	if err := ioutil.WriteFile(configPath, []byte(configContent), 0644); err != nil {
-		fmt.Printf("Error: Tidak dapat menulis file konfigurasi: %s\n", err)
+	file, site, err := caddyfile.LoadSite(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			fmt.Printf("Error: Proxy tidak ditemukan: %s\n", domain)
+		} else {
+			fmt.Printf("Error: Tidak dapat membaca file konfigurasi: %s\n", err)
+		}
+		return
+	}
+
+	cfg := parseConfig(domain, site)
+	if err := change(&cfg); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+
+	// Tulis secara atomik dan validasi, konfigurasi lama dipulihkan jika tidak valid
+	cfg.apply(site)
+	if err := caddy.ReplaceConfig(configPath, []byte(file.String())); err != nil {
+		fmt.Printf("Error: Perubahan dibatalkan: %s\n", err)
 		return
 	}
 
@@ -45,7 +244,7 @@ func Add(domain, target string) {
 		fmt.Printf("Peringatan: Tidak dapat memuat ulang Caddy: %s\n", err)
 	}
 
-	fmt.Printf("Situs proxy %s -> %s berhasil dibuat\n", domain, target)
+	fmt.Printf("Konfigurasi proxy %s berhasil diperbarui\n", domain)
 }
 
 // Remove removes an existing proxy
@@ -90,28 +289,55 @@ func List() {
 		return
 	}
 
-	proxyCount := 0
-	fmt.Println("Situs proxy yang dikonfigurasi:")
+	// Baca semua proxy lebih dulu agar semua target diperiksa bersamaan
+	type proxyEntry struct {
+		domain  string
+		site    *caddyfile.Node
+		cfg     Config
+		loadErr error
+	}
+	entries := []proxyEntry{}
+	targets := []string{}
 	for _, file := range files {
 		if !file.IsDir() && strings.HasPrefix(file.Name(), "proxy.") && strings.HasSuffix(file.Name(), ".conf") {
 			domain := strings.TrimPrefix(file.Name(), "proxy.")
 			domain = strings.TrimSuffix(domain, ".conf")
 
 			// Baca file untuk mendapatkan target
-			content, err := ioutil.ReadFile(filepath.Join(siteConfigDir, file.Name()))
-			if err != nil {
-				fmt.Printf("- %s -> [Error membaca target]\n", domain)
-				continue
+			_, site, err := caddyfile.LoadSite(filepath.Join(siteConfigDir, file.Name()))
+			entry := proxyEntry{domain: domain, site: site, loadErr: err}
+			if err == nil {
+				// Ekstrak upstream dari konfigurasi
+				entry.cfg = parseConfig(domain, site)
+				targets = append(targets, entry.cfg.Upstreams...)
 			}
+			entries = append(entries, entry)
+		}
+	}
+	reachable := checkTargets(targets)
 
-			// Ekstrak target dari konfigurasi
-			target := extractTarget(string(content))
-			fmt.Printf("- %s -> %s\n", domain, target)
-			proxyCount++
+	fmt.Println("Situs proxy yang dikonfigurasi:")
+	for _, entry := range entries {
+		if entry.loadErr != nil {
+			fmt.Printf("- %s -> [Error membaca target]\n", entry.domain)
+			continue
+		}
+		cfg := entry.cfg
+		target := "[Target tidak ditemukan]"
+		if len(cfg.Upstreams) > 0 {
+			target = strings.Join(cfg.Upstreams, ", ")
+		}
+		if cfg.LBPolicy != "" {
+			target += " (" + cfg.LBPolicy + ")"
 		}
+		modules := []string{}
+		for _, node := range entry.site.FindAll("import") {
+			modules = append(modules, node.Arg(0))
+		}
+		fmt.Printf("- %s -> %s [%s], modul: %s\n", entry.domain, target, targetStatus(cfg.Upstreams, reachable), valueOrDash(strings.Join(modules, ", ")))
 	}
 
-	if proxyCount == 0 {
+	if len(entries) == 0 {
 		fmt.Println("Tidak ada situs proxy yang dikonfigurasi")
 	}
 }
@@ -122,20 +348,29 @@ func isValidDomain(domain string) bool {
 	return len(domain) > 0 && !strings.Contains(domain, " ") && strings.Contains(domain, ".")
 }
 
-// isValidTarget memeriksa apakah target valid
-func isValidTarget(target string) bool {
-	// Implementasi sederhana, bisa ditingkatkan dengan validasi yang lebih baik
-	return len(target) > 0 && !strings.Contains(target, " ")
+// valueOrDash mengembalikan tanda strip untuk nilai kosong
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
 }
 
-// extractTarget mengekstrak target dari konfigurasi
-func extractTarget(config string) string {
-	lines := strings.Split(config, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "reverse_proxy ") {
-			return strings.TrimPrefix(line, "reverse_proxy ")
+// removeName menghapus nilai dari daftar
+func removeName(names []string, name string) []string {
+	result := []string{}
+	for _, item := range names {
+		if item != name {
+			result = append(result, item)
 		}
 	}
-	return "[Target tidak ditemukan]"
+	return result
+}
+
+// onOff menampilkan nilai boolean sebagai on atau off
+func onOff(value bool) string {
+	if value {
+		return "on"
+	}
+	return "off"
 }
@@ -0,0 +1,222 @@
+package proxy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	accessRules "github.com/doko89/webpanel/internal/site"
+	"github.com/doko89/webpanel/pkg/caddy"
+	"github.com/doko89/webpanel/pkg/caddyfile"
+)
+
+// terminalDirectives adalah direktif yang melayani sisa permintaan di luar rute
+var terminalDirectives = []string{"file_server", "php_fastcgi", "reverse_proxy"}
+
+// Route is a path inside a site that is forwarded to another target
+type Route struct {
+	Path        string
+	Target      string
+	StripPrefix bool
+	Service     string // layanan yang menjadi target, dicatat sebagai komentar service:<nama>
+}
+
+// AddRoute forwards a path of an existing site to a target
+func AddRoute(domain, path, target string, stripPrefix, check bool) {
+	fmt.Printf("Adding route %s -> %s for domain: %s\n", path, target, domain)
+	path = normalizeRoutePath(path)
+	if !strings.HasPrefix(path, "/") {
+		fmt.Printf("Error: Path harus diawali dengan /: %s\n", path)
+		return
+	}
+	target, service, err := resolveTarget(target)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	if _, err := parseTarget(target); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	if check {
+		if err := checkTarget(target); err != nil {
+			fmt.Printf("Error: %s\n", err)
+			return
+		}
+	}
+
+	updateRoutes(domain, func(routes []Route) ([]Route, error) {
+		for _, route := range routes {
+			if route.Path == path {
+				return nil, fmt.Errorf("rute %s sudah ada, hapus terlebih dahulu", path)
+			}
+		}
+		return append(routes, Route{Path: path, Target: target, StripPrefix: stripPrefix, Service: service}), nil
+	})
+}
+
+// RemoveRoute removes a path route from a site
+func RemoveRoute(domain, path string) {
+	fmt.Printf("Removing route %s for domain: %s\n", path, domain)
+	path = normalizeRoutePath(path)
+
+	updateRoutes(domain, func(routes []Route) ([]Route, error) {
+		remaining := []Route{}
+		for _, route := range routes {
+			if route.Path != path {
+				remaining = append(remaining, route)
+			}
+		}
+		if len(remaining) == len(routes) {
+			return nil, fmt.Errorf("rute %s tidak ditemukan", path)
+		}
+		return remaining, nil
+	})
+}
+
+// ListRoutes displays the path routes of a site
+func ListRoutes(domain string) {
+	fmt.Printf("Listing routes for domain: %s\n", domain)
+	configPath := routeConfigPath(domain)
+	if configPath == "" {
+		fmt.Printf("Error: Domain tidak ditemukan: %s\n", domain)
+		return
+	}
+	_, site, err := caddyfile.LoadSite(configPath)
+	if err != nil {
+		fmt.Printf("Error: Tidak dapat membaca file konfigurasi: %s\n", err)
+		return
+	}
+
+	routes := parseRoutes(site)
+	if len(routes) == 0 {
+		fmt.Printf("Tidak ada rute yang dikonfigurasi untuk %s\n", domain)
+		return
+	}
+
+	fmt.Printf("Rute untuk %s:\n", domain)
+	for _, route := range routes {
+		suffix := ""
+		if route.StripPrefix {
+			suffix = " (strip-prefix)"
+		}
+		if route.Service != "" {
+			suffix += " (" + servicePrefix + route.Service + ")"
+		}
+		fmt.Printf("- %s -> %s%s\n", route.Path, route.Target, suffix)
+	}
+}
+
+// updateRoutes membaca rute situs, mengubahnya dan menulis ulang semua blok rute secara berurutan
+func updateRoutes(domain string, change func(routes []Route) ([]Route, error)) {
+	configPath := routeConfigPath(domain)
+	if configPath == "" {
+		fmt.Printf("Error: Domain tidak ditemukan: %s\n", domain)
+		return
+	}
+	file, site, err := caddyfile.LoadSite(configPath)
+	if err != nil {
+		fmt.Printf("Error: Tidak dapat membaca file konfigurasi: %s\n", err)
+		return
+	}
+
+	routes, err := change(parseRoutes(site))
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+
+	// Hanya hapus blok yang dikenali sebagai rute agar blok handle lain tetap utuh
+	site.Remove(func(node *caddyfile.Node) bool {
+		_, ok := routeFromNode(node)
+		return ok
+	})
+	site.Insert(renderRoutes(routes), terminalDirectives...)
+	// Aturan akses situs juga harus berlaku di dalam blok rute yang baru
+	accessRules.ApplyAccessToRoutes(site)
+
+	// Tulis secara atomik dan validasi, konfigurasi lama dipulihkan jika tidak valid
+	if err := caddy.ReplaceConfig(configPath, []byte(file.String())); err != nil {
+		fmt.Printf("Error: Perubahan dibatalkan: %s\n", err)
+		return
+	}
+
+	// Muat ulang Caddy
+	if err := caddy.Reload(); err != nil {
+		fmt.Printf("Peringatan: Tidak dapat memuat ulang Caddy: %s\n", err)
+	}
+
+	fmt.Printf("Rute untuk %s berhasil diperbarui\n", domain)
+}
+
+// renderRoutes menyusun blok handle untuk semua rute, path yang lebih spesifik lebih dulu
+func renderRoutes(routes []Route) []*caddyfile.Node {
+	sort.SliceStable(routes, func(i, j int) bool {
+		return len(strings.TrimSuffix(routes[i].Path, "*")) > len(strings.TrimSuffix(routes[j].Path, "*"))
+	})
+
+	nodes := []*caddyfile.Node{}
+	for _, route := range routes {
+		directive := "handle"
+		if route.StripPrefix {
+			directive = "handle_path"
+		}
+		reverseProxy := caddyfile.New("reverse_proxy", route.Target)
+		if route.Service != "" {
+			reverseProxy.Comment = withServices("", []string{route.Service})
+		}
+		nodes = append(nodes, caddyfile.New(directive, route.Path).Add(reverseProxy))
+	}
+	return nodes
+}
+
+// parseRoutes membaca blok handle dan handle_path yang hanya berisi reverse_proxy
+func parseRoutes(site *caddyfile.Node) []Route {
+	routes := []Route{}
+	for _, node := range site.Block {
+		if route, ok := routeFromNode(node); ok {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// routeFromNode mengenali blok rute berbasis path yang hanya berisi satu reverse_proxy dengan satu target
+func routeFromNode(node *caddyfile.Node) (Route, bool) {
+	name := node.Name()
+	if (name != "handle" && name != "handle_path") || len(node.Tokens) != 2 || !strings.HasPrefix(node.Arg(0), "/") {
+		return Route{}, false
+	}
+	directives := []*caddyfile.Node{}
+	for _, child := range node.Block {
+		if len(child.Tokens) > 0 && !accessRules.IsAccessDirective(child) {
+			directives = append(directives, child)
+		}
+	}
+	if len(directives) != 1 || directives[0].Name() != "reverse_proxy" || len(directives[0].Tokens) != 2 || directives[0].Open {
+		return Route{}, false
+	}
+	route := Route{Path: node.Arg(0), Target: directives[0].Arg(0), StripPrefix: name == "handle_path"}
+	if services := commentServices(directives[0].Comment); len(services) > 0 {
+		route.Service = services[0]
+	}
+	return route, true
+}
+
+// normalizeRoutePath menambahkan wildcard agar path mencakup semua subpath
+func normalizeRoutePath(path string) string {
+	if strings.Contains(path, "*") || path == "/" {
+		return path
+	}
+	return strings.TrimSuffix(path, "/") + "/*"
+}
+
+// routeConfigPath mencari konfigurasi situs atau proxy untuk domain; situs
+// pengalihan tidak memiliki konten yang dapat dirutekan
+func routeConfigPath(domain string) string {
+	config, ok := caddy.FindSiteConfig(domain)
+	if !ok || config.Kind == caddy.KindRedirect {
+		return ""
+	}
+	return config.Path
+}
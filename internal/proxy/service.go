@@ -0,0 +1,412 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/doko89/webpanel/pkg/caddy"
+	"github.com/doko89/webpanel/pkg/caddyfile"
+)
+
+const (
+	servicesFile  = "/etc/webpanel/services.json"
+	servicePrefix = "service:"
+)
+
+var serviceNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Service is a named backend whose address is managed by webpanel
+type Service struct {
+	Name   string `json:"name"`
+	Target string `json:"target"`
+	Unit   string `json:"unit,omitempty"`
+}
+
+// SocketTarget converts a Unix socket path into a reverse proxy target
+func SocketTarget(path string) string {
+	return "unix/" + path
+}
+
+// AddService registers a named backend that proxies can use as service:<name>
+func AddService(name, target, unit string, check bool) {
+	fmt.Printf("Adding service %s -> %s\n", name, target)
+	if !serviceNamePattern.MatchString(name) {
+		fmt.Printf("Error: Nama layanan tidak valid: %s (gunakan huruf kecil, angka, - dan _)\n", name)
+		return
+	}
+	target = normalizeTarget(target)
+	if err := validateServiceTarget(target, check); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	if unit != "" && !strings.Contains(unit, ".") {
+		unit += ".service"
+	}
+
+	services, err := loadServices()
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	if _, ok := services[name]; ok {
+		fmt.Printf("Error: Layanan %s sudah ada, gunakan 'proxy service set' untuk mengubah alamatnya\n", name)
+		return
+	}
+	services[name] = Service{Name: name, Target: target, Unit: unit}
+	if err := saveServices(services); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+
+	fmt.Printf("Layanan %s berhasil ditambahkan, gunakan target %s%s\n", name, servicePrefix, name)
+}
+
+// SetService changes the address of a service and updates every proxy and
+// route that references it with one validation and reload
+func SetService(name, target string, check bool) {
+	fmt.Printf("Setting service %s -> %s\n", name, target)
+	services, err := loadServices()
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	service, ok := services[name]
+	if !ok {
+		fmt.Printf("Error: Layanan tidak ditemukan: %s\n", name)
+		return
+	}
+	target = normalizeTarget(target)
+	if err := validateServiceTarget(target, check); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+
+	// Kumpulkan perubahan semua konfigurasi yang mereferensikan layanan
+	oldTarget := service.Target
+	files := map[string][]byte{}
+	proxies, sites := []string{}, []string{}
+	for _, config := range serviceConfigs() {
+		changed := false
+		if config.kind == caddy.KindProxy {
+			node := config.site.Find("reverse_proxy")
+			if node != nil && contains(commentServices(node.Comment), name) {
+				cfg := parseConfig(config.domain, config.site)
+				cfg.Upstreams = replaceTarget(cfg.Upstreams, oldTarget, target)
+				if err := cfg.Validate(); err != nil {
+					fmt.Printf("Error: %s: %s\n", config.domain, err)
+					return
+				}
+				cfg.apply(config.site)
+				proxies = append(proxies, config.domain)
+				changed = true
+			}
+		}
+		routeChanged := false
+		for _, node := range config.site.Block {
+			route, ok := routeFromNode(node)
+			if !ok || route.Service != name {
+				continue
+			}
+			proxyNode := node.Find("reverse_proxy")
+			proxyNode.Tokens = caddyfile.New("reverse_proxy", target).Tokens
+			routeChanged = true
+		}
+		if routeChanged {
+			sites = append(sites, config.domain)
+		}
+		if changed || routeChanged {
+			files[config.path] = []byte(config.file.String())
+		}
+	}
+
+	// Tulis semua konfigurasi sekaligus, semuanya dipulihkan jika tidak valid
+	if len(files) > 0 {
+		if err := caddy.ReplaceConfigs(files); err != nil {
+			fmt.Printf("Error: Perubahan dibatalkan: %s\n", err)
+			return
+		}
+	}
+
+	service.Target = target
+	services[name] = service
+	if err := saveServices(services); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		fmt.Printf("Catatan: Konfigurasi sudah menggunakan %s, jalankan ulang perintah ini setelah masalah diperbaiki\n", target)
+		return
+	}
+
+	if len(files) > 0 {
+		if err := caddy.Reload(); err != nil {
+			fmt.Printf("Peringatan: Tidak dapat memuat ulang Caddy: %s\n", err)
+		}
+	}
+
+	fmt.Printf("Layanan %s berhasil diperbarui (%d proxy, %d situs)\n", name, len(proxies), len(sites))
+}
+
+// RemoveService removes a service that is no longer used
+func RemoveService(name string) {
+	fmt.Printf("Removing service: %s\n", name)
+	services, err := loadServices()
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	if _, ok := services[name]; !ok {
+		fmt.Printf("Error: Layanan tidak ditemukan: %s\n", name)
+		return
+	}
+	proxies, sites := serviceUsers(name)
+	if users := append(proxies, sites...); len(users) > 0 {
+		fmt.Printf("Error: Layanan %s masih digunakan oleh: %s\n", name, strings.Join(users, ", "))
+		return
+	}
+
+	delete(services, name)
+	if err := saveServices(services); err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	fmt.Printf("Layanan %s berhasil dihapus\n", name)
+}
+
+// ListServices displays the registered services with their unit and connection status
+func ListServices() {
+	fmt.Println("Listing all services:")
+	services, err := loadServices()
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	if len(services) == 0 {
+		fmt.Println("Tidak ada layanan yang terdaftar")
+		return
+	}
+
+	names := []string{}
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	targets := []string{}
+	for _, name := range names {
+		targets = append(targets, services[name].Target)
+	}
+	reachable := checkTargets(targets)
+	for _, name := range names {
+		service := services[name]
+		unit := "-"
+		if service.Unit != "" {
+			unit = service.Unit + " (" + unitState(service.Unit) + ")"
+		}
+		proxies, sites := serviceUsers(name)
+		fmt.Printf("- %s -> %s [%s], unit: %s, digunakan oleh: %s\n", name, service.Target,
+			targetStatus([]string{service.Target}, reachable), unit, valueOrDash(strings.Join(append(proxies, sites...), ", ")))
+	}
+}
+
+// resolveTarget mengganti referensi service:<nama> dan path socket dengan
+// alamat target; nama layanan dikembalikan agar dapat dicatat di konfigurasi
+func resolveTarget(raw string) (string, string, error) {
+	if !strings.HasPrefix(raw, servicePrefix) {
+		return normalizeTarget(raw), "", nil
+	}
+	name := strings.TrimPrefix(raw, servicePrefix)
+	services, err := loadServices()
+	if err != nil {
+		return "", "", err
+	}
+	service, ok := services[name]
+	if !ok {
+		return "", "", fmt.Errorf("layanan tidak ditemukan: %s (lihat 'proxy service list')", name)
+	}
+	return service.Target, name, nil
+}
+
+// resolveTargets menerapkan resolveTarget pada semua target dan mengembalikan
+// nama layanan yang direferensikan
+func resolveTargets(targets []string) ([]string, []string, error) {
+	resolved, names := []string{}, []string{}
+	for _, raw := range targets {
+		target, name, err := resolveTarget(raw)
+		if err != nil {
+			return nil, nil, err
+		}
+		resolved = append(resolved, target)
+		if name != "" && !contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return resolved, names, nil
+}
+
+// normalizeTarget mengubah path socket absolut menjadi target unix//
+func normalizeTarget(raw string) string {
+	if strings.HasPrefix(raw, "/") {
+		return SocketTarget(raw)
+	}
+	return raw
+}
+
+// validateServiceTarget memeriksa format target layanan dan, jika diminta, koneksinya
+func validateServiceTarget(target string, check bool) error {
+	if check {
+		return checkTarget(target)
+	}
+	_, err := parseTarget(target)
+	return err
+}
+
+// replaceTarget mengganti target lama dengan target baru di daftar upstream
+func replaceTarget(targets []string, oldTarget, newTarget string) []string {
+	result := []string{}
+	for _, target := range targets {
+		if target == oldTarget {
+			target = newTarget
+		}
+		if !contains(result, target) {
+			result = append(result, target)
+		}
+	}
+	return result
+}
+
+// serviceConfig adalah konfigurasi situs atau proxy yang sudah diurai
+type serviceConfig struct {
+	domain string
+	kind   string
+	path   string
+	file   *caddyfile.File
+	site   *caddyfile.Node
+}
+
+// serviceConfigs membaca semua konfigurasi situs dan proxy yang dapat
+// mereferensikan layanan
+func serviceConfigs() []serviceConfig {
+	configs := []serviceConfig{}
+	siteConfigs, err := caddy.SiteConfigs()
+	if err != nil {
+		return configs
+	}
+	for _, config := range siteConfigs {
+		if config.Kind == caddy.KindRedirect {
+			continue
+		}
+		file, site, err := caddyfile.LoadSite(config.Path)
+		if err != nil {
+			continue
+		}
+		configs = append(configs, serviceConfig{config.Domain, config.Kind, config.Path, file, site})
+	}
+	return configs
+}
+
+// serviceUsers mencari proxy dan situs (melalui rute) yang mereferensikan
+// layanan dengan komentar service:<nama>
+func serviceUsers(name string) ([]string, []string) {
+	proxies, sites := []string{}, []string{}
+	for _, config := range serviceConfigs() {
+		if config.kind == caddy.KindProxy {
+			if node := config.site.Find("reverse_proxy"); node != nil && contains(commentServices(node.Comment), name) {
+				proxies = append(proxies, config.domain)
+				continue
+			}
+		}
+		for _, route := range parseRoutes(config.site) {
+			if route.Service == name {
+				sites = append(sites, config.domain)
+				break
+			}
+		}
+	}
+	return proxies, sites
+}
+
+// commentServices membaca nama layanan dari komentar seperti "# service:api"
+func commentServices(comment string) []string {
+	names := []string{}
+	for _, word := range strings.Fields(strings.TrimPrefix(comment, "#")) {
+		if strings.HasPrefix(word, servicePrefix) {
+			names = append(names, strings.TrimPrefix(word, servicePrefix))
+		}
+	}
+	return names
+}
+
+// withServices mengganti penanda service:<nama> di komentar dan
+// mempertahankan isi komentar lainnya
+func withServices(comment string, names []string) string {
+	words := []string{}
+	for _, word := range strings.Fields(strings.TrimPrefix(comment, "#")) {
+		if !strings.HasPrefix(word, servicePrefix) {
+			words = append(words, word)
+		}
+	}
+	for _, name := range names {
+		words = append(words, servicePrefix+name)
+	}
+	if len(words) == 0 {
+		return ""
+	}
+	return "# " + strings.Join(words, " ")
+}
+
+// unitState mengembalikan status unit systemd, misalnya active atau failed
+func unitState(unit string) string {
+	// systemctl is-active keluar dengan status bukan nol untuk unit yang tidak aktif
+	output, _ := exec.Command("systemctl", "is-active", unit).Output()
+	state := strings.TrimSpace(string(output))
+	if state == "" {
+		return "unknown"
+	}
+	return state
+}
+
+// loadServices membaca daftar layanan yang terdaftar
+func loadServices() (map[string]Service, error) {
+	services := map[string]Service{}
+	content, err := ioutil.ReadFile(servicesFile)
+	if os.IsNotExist(err) {
+		return services, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tidak dapat membaca daftar layanan: %w", err)
+	}
+
+	list := []Service{}
+	if err := json.Unmarshal(content, &list); err != nil {
+		return nil, fmt.Errorf("daftar layanan %s tidak valid: %w", servicesFile, err)
+	}
+	for _, service := range list {
+		services[service.Name] = service
+	}
+	return services, nil
+}
+
+// saveServices menulis daftar layanan, diurutkan berdasarkan nama
+func saveServices(services map[string]Service) error {
+	list := []Service{}
+	for _, service := range services {
+		list = append(list, service)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	content, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(servicesFile), 0755); err != nil {
+		return fmt.Errorf("tidak dapat membuat direktori %s: %w", filepath.Dir(servicesFile), err)
+	}
+	if err := ioutil.WriteFile(servicesFile, append(content, '\n'), 0644); err != nil {
+		return fmt.Errorf("tidak dapat menulis daftar layanan: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// checkTimeout adalah batas waktu pemeriksaan koneksi ke target
+const checkTimeout = 3 * time.Second
+
+var (
+	targetSchemes   = []string{"http", "https", "h2c"}
+	hostnamePattern = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9_-]*[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9_-]*[a-zA-Z0-9])?)*$`)
+)
+
+// target adalah alamat upstream reverse_proxy yang sudah diurai
+type target struct {
+	scheme string // http, https atau h2c, kosong jika tidak ditulis
+	host   string
+	port   string
+	socket string // path socket Unix untuk target unix//
+}
+
+// parseTarget mengurai target dalam bentuk host:port, URL dengan skema atau unix//path
+func parseTarget(raw string) (target, error) {
+	if raw == "" || strings.ContainsAny(raw, " \t") {
+		return target{}, fmt.Errorf("target tidak valid: %q", raw)
+	}
+
+	if strings.HasPrefix(raw, "unix/") {
+		socket := strings.TrimPrefix(raw, "unix/")
+		if !strings.HasPrefix(socket, "/") {
+			return target{}, fmt.Errorf("target tidak valid: %s (path socket harus absolut, contoh unix//run/app.sock)", raw)
+		}
+		return target{socket: socket}, nil
+	}
+
+	t := target{}
+	address := raw
+	if strings.Contains(raw, "://") {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return target{}, fmt.Errorf("target tidak valid: %s", raw)
+		}
+		if !contains(targetSchemes, u.Scheme) {
+			return target{}, fmt.Errorf("skema target tidak didukung: %s (gunakan %s)", u.Scheme, strings.Join(targetSchemes, ", "))
+		}
+		if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.User != nil {
+			return target{}, fmt.Errorf("target tidak boleh berisi path, query atau kredensial: %s", raw)
+		}
+		t.scheme = u.Scheme
+		address = u.Host
+	}
+
+	// Alamat IPv6 tanpa kurung siku tidak memiliki port
+	if strings.HasPrefix(address, "[") || strings.Count(address, ":") == 1 {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return target{}, fmt.Errorf("target tidak valid: %s", raw)
+		}
+		number, err := strconv.Atoi(port)
+		if err != nil || number < 1 || number > 65535 {
+			return target{}, fmt.Errorf("port tidak valid pada target %s: harus antara 1 dan 65535", raw)
+		}
+		t.host, t.port = host, port
+	} else {
+		t.host = address
+	}
+
+	if t.host != "" && net.ParseIP(t.host) == nil && !hostnamePattern.MatchString(t.host) {
+		return target{}, fmt.Errorf("host tidak valid pada target %s: %s", raw, t.host)
+	}
+	if t.host == "" && t.port == "" {
+		return target{}, fmt.Errorf("target tidak valid: %s", raw)
+	}
+	return t, nil
+}
+
+// dialAddress mengembalikan jaringan dan alamat untuk menghubungi target
+func (t target) dialAddress() (string, string) {
+	if t.socket != "" {
+		return "unix", t.socket
+	}
+	host, port := t.host, t.port
+	if host == "" {
+		host = "localhost"
+	}
+	if port == "" {
+		port = "80"
+		if t.scheme == "https" {
+			port = "443"
+		}
+	}
+	return "tcp", net.JoinHostPort(host, port)
+}
+
+// check memeriksa apakah target dapat dihubungi
+func (t target) check() error {
+	if t.socket != "" {
+		info, err := os.Stat(t.socket)
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSocket == 0 {
+			return fmt.Errorf("%s bukan socket Unix", t.socket)
+		}
+	}
+	network, address := t.dialAddress()
+	conn, err := net.DialTimeout(network, address, checkTimeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// checkTarget mengurai target dan memastikan target dapat dihubungi sebelum konfigurasi ditulis
+func checkTarget(raw string) error {
+	t, err := parseTarget(raw)
+	if err != nil {
+		return err
+	}
+	if err := t.check(); err != nil {
+		return fmt.Errorf("target %s tidak dapat dihubungi: %s (gunakan --no-check untuk melewati)", raw, err)
+	}
+	return nil
+}
+
+// checkTargets memeriksa koneksi ke semua target secara bersamaan agar waktu
+// tunggu tidak bertambah untuk setiap target yang mati
+func checkTargets(targets []string) map[string]bool {
+	reachable := map[string]bool{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, raw := range targets {
+		if _, ok := reachable[raw]; ok {
+			continue
+		}
+		reachable[raw] = false
+		wg.Add(1)
+		go func(raw string) {
+			defer wg.Done()
+			t, err := parseTarget(raw)
+			up := err == nil && t.check() == nil
+			mu.Lock()
+			reachable[raw] = up
+			mu.Unlock()
+		}(raw)
+	}
+	wg.Wait()
+	return reachable
+}
+
+// targetStatus merangkum status koneksi semua upstream dari hasil checkTargets
+func targetStatus(upstreams []string, reachable map[string]bool) string {
+	up := 0
+	for _, upstream := range upstreams {
+		if reachable[upstream] {
+			up++
+		}
+	}
+	switch {
+	case len(upstreams) == 0:
+		return "-"
+	case up == len(upstreams):
+		return "up"
+	case up == 0:
+		return "down"
+	default:
+		return fmt.Sprintf("%d/%d up", up, len(upstreams))
+	}
+}
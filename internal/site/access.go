@@ -0,0 +1,322 @@
+package site
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/doko89/webpanel/pkg/caddy"
+	"github.com/doko89/webpanel/pkg/caddyfile"
+)
+
+const accessMatcherPrefix = "@access_"
+
+// accessBefore adalah direktif yang didahului oleh blok aturan akses
+var accessBefore = []string{"handle", "handle_path", "file_server", "php_fastcgi", "reverse_proxy"}
+
+// AccessRule allows or denies a list of networks for a path of a site
+type AccessRule struct {
+	Action  string // allow atau deny
+	Path    string // kosong untuk seluruh situs
+	CIDRs   []string
+	matcher string // nama matcher di konfigurasi, misalnya @access_0
+}
+
+// AllowAccess restricts a site or path to the given network; requests from
+// other addresses are rejected
+func AllowAccess(domain, cidr, path string) {
+	fmt.Printf("Allowing %s for domain: %s\n", cidr, domain)
+	addAccess(domain, "allow", cidr, path)
+}
+
+// DenyAccess rejects requests from the given network for a site or path
+func DenyAccess(domain, cidr, path string) {
+	fmt.Printf("Denying %s for domain: %s\n", cidr, domain)
+	addAccess(domain, "deny", cidr, path)
+}
+
+// RemoveAccess removes a network from the access rules of a site or path
+func RemoveAccess(domain, cidr, path string) {
+	fmt.Printf("Removing access rule %s for domain: %s\n", cidr, domain)
+	network, err := parseNetwork(cidr)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	path = normalizeAccessPath(path)
+
+	updateAccess(domain, func(rules []AccessRule) ([]AccessRule, error) {
+		found := false
+		remaining := []AccessRule{}
+		for _, rule := range rules {
+			if rule.Path == path {
+				cidrs := []string{}
+				for _, c := range rule.CIDRs {
+					if c == network {
+						found = true
+					} else {
+						cidrs = append(cidrs, c)
+					}
+				}
+				rule.CIDRs = cidrs
+			}
+			if len(rule.CIDRs) > 0 {
+				remaining = append(remaining, rule)
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("aturan akses untuk %s tidak ditemukan", network)
+		}
+		return remaining, nil
+	})
+}
+
+// ListAccess displays the access rules of a site
+func ListAccess(domain string) {
+	fmt.Printf("Listing access rules for domain: %s\n", domain)
+	configPath := accessConfigPath(domain)
+	if configPath == "" {
+		fmt.Printf("Error: Domain tidak ditemukan: %s\n", domain)
+		return
+	}
+	_, site, err := caddyfile.LoadSite(configPath)
+	if err != nil {
+		fmt.Printf("Error: Tidak dapat membaca file konfigurasi: %s\n", err)
+		return
+	}
+
+	rules := parseAccessRules(site)
+	if len(rules) == 0 {
+		fmt.Printf("Tidak ada aturan akses untuk %s\n", domain)
+		return
+	}
+	fmt.Printf("Aturan akses untuk %s:\n", domain)
+	for _, rule := range rules {
+		path := rule.Path
+		if path == "" {
+			path = "(seluruh situs)"
+		}
+		fmt.Printf("- %-5s %s: %s\n", rule.Action, path, strings.Join(rule.CIDRs, ", "))
+	}
+}
+
+// addAccess menambahkan jaringan ke aturan allow atau deny untuk path
+func addAccess(domain, action, cidr, path string) {
+	network, err := parseNetwork(cidr)
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+	if path != "" && !strings.HasPrefix(path, "/") {
+		fmt.Printf("Error: Path harus diawali dengan /: %s\n", path)
+		return
+	}
+	path = normalizeAccessPath(path)
+
+	updateAccess(domain, func(rules []AccessRule) ([]AccessRule, error) {
+		for i, rule := range rules {
+			if rule.Path != path {
+				continue
+			}
+			if contains(rule.CIDRs, network) {
+				return nil, fmt.Errorf("%s sudah ada di aturan %s untuk path ini", network, rule.Action)
+			}
+			if rule.Action == action {
+				rules[i].CIDRs = append(rules[i].CIDRs, network)
+				return rules, nil
+			}
+		}
+		return append(rules, AccessRule{Action: action, Path: path, CIDRs: []string{network}}), nil
+	})
+}
+
+// updateAccess membaca aturan akses, mengubahnya dan menulis ulang semua blok aturan
+func updateAccess(domain string, change func(rules []AccessRule) ([]AccessRule, error)) {
+	configPath := accessConfigPath(domain)
+	if configPath == "" {
+		fmt.Printf("Error: Domain tidak ditemukan: %s\n", domain)
+		return
+	}
+	file, site, err := caddyfile.LoadSite(configPath)
+	if err != nil {
+		fmt.Printf("Error: Tidak dapat membaca file konfigurasi: %s\n", err)
+		return
+	}
+
+	rules, err := change(parseAccessRules(site))
+	if err != nil {
+		fmt.Printf("Error: %s\n", err)
+		return
+	}
+
+	site.Remove(IsAccessDirective)
+	site.Insert(renderAccessRules(rules), accessBefore...)
+	ApplyAccessToRoutes(site)
+
+	// Tulis secara atomik dan validasi, konfigurasi lama dipulihkan jika tidak valid
+	if err := caddy.ReplaceConfig(configPath, []byte(file.String())); err != nil {
+		fmt.Printf("Error: Perubahan dibatalkan: %s\n", err)
+		return
+	}
+
+	// Muat ulang Caddy
+	if err := caddy.Reload(); err != nil {
+		fmt.Printf("Peringatan: Tidak dapat memuat ulang Caddy: %s\n", err)
+	}
+
+	fmt.Printf("Aturan akses untuk %s berhasil diperbarui\n", domain)
+}
+
+// IsAccessDirective reports whether a node is a matcher or respond directive
+// generated for an access rule
+func IsAccessDirective(node *caddyfile.Node) bool {
+	return strings.HasPrefix(node.Name(), accessMatcherPrefix) ||
+		(node.Name() == "respond" && strings.HasPrefix(node.Arg(0), accessMatcherPrefix))
+}
+
+// ApplyAccessToRoutes repeats the access rules of a site inside its handle and
+// handle_path blocks. Caddy runs handle blocks before the site-level respond,
+// so without this every path route would bypass the rules.
+func ApplyAccessToRoutes(site *caddyfile.Node) {
+	rules := parseAccessRules(site)
+	for _, node := range site.Block {
+		name := node.Name()
+		if !node.Open || (name != "handle" && name != "handle_path") {
+			continue
+		}
+		node.Remove(IsAccessDirective)
+
+		nodes := []*caddyfile.Node{}
+		for i, rule := range rules {
+			// handle_path menghapus prefix sebelum isi bloknya dijalankan sehingga
+			// matcher path situs tidak lagi cocok dan diganti matcher lokal
+			if name == "handle_path" && rule.Path != "" {
+				nodes = append(nodes, routeAccessRule(rule, node.Arg(0), i)...)
+				continue
+			}
+			nodes = append(nodes, caddyfile.New("respond", rule.matcher, "Akses ditolak", "403"))
+		}
+		node.Block = append(nodes, node.Block...)
+	}
+}
+
+// routeAccessRule menyusun matcher lokal dan respond untuk aturan dengan path
+// di dalam blok handle_path, relatif terhadap prefix yang dihapus
+func routeAccessRule(rule AccessRule, routePath string, index int) []*caddyfile.Node {
+	prefix := normalizeAccessPath(routePath)
+	matcher := caddyfile.New(fmt.Sprintf("%s%d_route", accessMatcherPrefix, index))
+	switch {
+	case rule.Path == prefix || strings.HasPrefix(prefix, rule.Path+"/"):
+		// Aturan mencakup seluruh rute
+	case strings.HasPrefix(rule.Path, prefix+"/"):
+		rel := strings.TrimPrefix(rule.Path, prefix)
+		matcher.Add(caddyfile.New("path", rel, rel+"/*"))
+	default:
+		return nil
+	}
+	matcher.Add(remoteIPMatcher(rule))
+	return []*caddyfile.Node{matcher, caddyfile.New("respond", matcher.Name(), "Akses ditolak", "403")}
+}
+
+// remoteIPMatcher menyusun matcher alamat klien untuk aturan; aturan allow
+// menolak semua alamat di luar jaringan yang diizinkan
+func remoteIPMatcher(rule AccessRule) *caddyfile.Node {
+	if rule.Action == "allow" {
+		return caddyfile.New(append([]string{"not", "remote_ip"}, rule.CIDRs...)...)
+	}
+	return caddyfile.New(append([]string{"remote_ip"}, rule.CIDRs...)...)
+}
+
+// renderAccessRules menyusun matcher dan respond untuk setiap aturan, path yang lebih spesifik lebih dulu
+func renderAccessRules(rules []AccessRule) []*caddyfile.Node {
+	sort.SliceStable(rules, func(i, j int) bool {
+		return len(rules[i].Path) > len(rules[j].Path)
+	})
+
+	nodes := []*caddyfile.Node{}
+	for i, rule := range rules {
+		matcher := caddyfile.New(fmt.Sprintf("%s%d", accessMatcherPrefix, i))
+		if rule.Path != "" {
+			matcher.Add(caddyfile.New("path", rule.Path, rule.Path+"/*"))
+		}
+		matcher.Add(remoteIPMatcher(rule))
+		nodes = append(nodes, matcher, caddyfile.New("respond", matcher.Name(), "Akses ditolak", "403"))
+	}
+	return nodes
+}
+
+// parseAccessRules membaca blok matcher aturan akses dari blok situs
+func parseAccessRules(site *caddyfile.Node) []AccessRule {
+	rules := []AccessRule{}
+	for _, node := range site.Block {
+		if !node.Open || !strings.HasPrefix(node.Name(), accessMatcherPrefix) {
+			continue
+		}
+		rule := AccessRule{matcher: node.Name()}
+		for _, child := range node.Block {
+			args := child.Args()
+			switch {
+			case child.Name() == "path" && len(args) > 0:
+				rule.Path = args[0]
+			case child.Name() == "remote_ip":
+				rule.Action, rule.CIDRs = "deny", args
+			case child.Name() == "not" && len(args) > 1 && args[0] == "remote_ip":
+				rule.Action, rule.CIDRs = "allow", args[1:]
+			}
+		}
+		if rule.Action != "" && len(rule.CIDRs) > 0 {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// parseNetwork memvalidasi CIDR atau alamat IP dan mengembalikan jaringan dalam bentuk CIDR
+func parseNetwork(value string) (string, error) {
+	if !strings.Contains(value, "/") {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return "", fmt.Errorf("alamat IP atau CIDR tidak valid: %s", value)
+		}
+		if ip.To4() != nil {
+			return ip.String() + "/32", nil
+		}
+		return ip.String() + "/128", nil
+	}
+
+	ip, network, err := net.ParseCIDR(value)
+	if err != nil {
+		return "", fmt.Errorf("CIDR tidak valid: %s", value)
+	}
+	if !ip.Equal(network.IP) {
+		fmt.Printf("Catatan: %s dinormalisasi menjadi %s\n", value, network)
+	}
+	return network.String(), nil
+}
+
+// normalizeAccessPath menghapus wildcard dan garis miring di akhir path
+func normalizeAccessPath(path string) string {
+	path = strings.TrimSuffix(strings.TrimSuffix(path, "*"), "/")
+	return path
+}
+
+// accessConfigPath mencari konfigurasi situs atau proxy untuk domain; situs
+// pengalihan tidak memiliki konten yang perlu dibatasi
+func accessConfigPath(domain string) string {
+	config, ok := caddy.FindSiteConfig(domain)
+	if !ok || config.Kind == caddy.KindRedirect {
+		return ""
+	}
+	return config.Path
+}
+
+// contains memeriksa apakah daftar berisi nilai
+func contains(items []string, value string) bool {
+	for _, item := range items {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
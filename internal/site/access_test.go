@@ -0,0 +1,194 @@
+package site
+
+import (
+	"testing"
+
+	"github.com/doko89/webpanel/pkg/caddyfile"
+)
+
+// writeAccess menulis ulang aturan akses situs seperti updateAccess
+func writeAccess(t *testing.T, src string, rules []AccessRule) string {
+	t.Helper()
+	file, err := caddyfile.Parse(src)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	site := file.Site()
+	site.Remove(IsAccessDirective)
+	site.Insert(renderAccessRules(rules), accessBefore...)
+	ApplyAccessToRoutes(site)
+	return file.String()
+}
+
+func TestApplyAccessToRoutes(t *testing.T) {
+	const src = "example.com {\n" +
+		"\troot * /apps/sites/example.com\n" +
+		"\thandle_path /admin/* {\n" +
+		"\t\treverse_proxy localhost:3000\n" +
+		"\t}\n" +
+		"\thandle_path /administrator/* {\n" +
+		"\t\treverse_proxy localhost:3001\n" +
+		"\t}\n" +
+		"\thandle_path /blog/* {\n" +
+		"\t\treverse_proxy localhost:4000\n" +
+		"\t}\n" +
+		"\thandle /api/* {\n" +
+		"\t\treverse_proxy localhost:5000\n" +
+		"\t}\n" +
+		"\thandle_path /* {\n" +
+		"\t\treverse_proxy localhost:6000\n" +
+		"\t}\n" +
+		"\tfile_server\n" +
+		"}\n"
+
+	tests := []struct {
+		name  string
+		rules []AccessRule
+		want  string
+	}{
+		{
+			name: "rules on the route prefix",
+			rules: []AccessRule{
+				{Action: "allow", Path: "/admin", CIDRs: []string{"10.0.0.0/8"}},
+				{Action: "deny", Path: "/admin", CIDRs: []string{"10.0.0.13/32"}},
+			},
+			want: "example.com {\n" +
+				"\troot * /apps/sites/example.com\n" +
+				"\t@access_0 {\n" +
+				"\t\tpath /admin /admin/*\n" +
+				"\t\tnot remote_ip 10.0.0.0/8\n" +
+				"\t}\n" +
+				"\trespond @access_0 \"Akses ditolak\" 403\n" +
+				"\t@access_1 {\n" +
+				"\t\tpath /admin /admin/*\n" +
+				"\t\tremote_ip 10.0.0.13/32\n" +
+				"\t}\n" +
+				"\trespond @access_1 \"Akses ditolak\" 403\n" +
+				"\thandle_path /admin/* {\n" +
+				"\t\t@access_0_route {\n" +
+				"\t\t\tnot remote_ip 10.0.0.0/8\n" +
+				"\t\t}\n" +
+				"\t\trespond @access_0_route \"Akses ditolak\" 403\n" +
+				"\t\t@access_1_route {\n" +
+				"\t\t\tremote_ip 10.0.0.13/32\n" +
+				"\t\t}\n" +
+				"\t\trespond @access_1_route \"Akses ditolak\" 403\n" +
+				"\t\treverse_proxy localhost:3000\n" +
+				"\t}\n" +
+				"\thandle_path /administrator/* {\n" +
+				"\t\treverse_proxy localhost:3001\n" +
+				"\t}\n" +
+				"\thandle_path /blog/* {\n" +
+				"\t\treverse_proxy localhost:4000\n" +
+				"\t}\n" +
+				"\thandle /api/* {\n" +
+				"\t\trespond @access_0 \"Akses ditolak\" 403\n" +
+				"\t\trespond @access_1 \"Akses ditolak\" 403\n" +
+				"\t\treverse_proxy localhost:5000\n" +
+				"\t}\n" +
+				"\thandle_path /* {\n" +
+				"\t\t@access_0_route {\n" +
+				"\t\t\tpath /admin /admin/*\n" +
+				"\t\t\tnot remote_ip 10.0.0.0/8\n" +
+				"\t\t}\n" +
+				"\t\trespond @access_0_route \"Akses ditolak\" 403\n" +
+				"\t\t@access_1_route {\n" +
+				"\t\t\tpath /admin /admin/*\n" +
+				"\t\t\tremote_ip 10.0.0.13/32\n" +
+				"\t\t}\n" +
+				"\t\trespond @access_1_route \"Akses ditolak\" 403\n" +
+				"\t\treverse_proxy localhost:6000\n" +
+				"\t}\n" +
+				"\tfile_server\n" +
+				"}\n",
+		},
+		{
+			name: "rule below the route prefix",
+			rules: []AccessRule{
+				{Action: "deny", Path: "/admin/users", CIDRs: []string{"192.0.2.0/24"}},
+			},
+			want: "example.com {\n" +
+				"\troot * /apps/sites/example.com\n" +
+				"\t@access_0 {\n" +
+				"\t\tpath /admin/users /admin/users/*\n" +
+				"\t\tremote_ip 192.0.2.0/24\n" +
+				"\t}\n" +
+				"\trespond @access_0 \"Akses ditolak\" 403\n" +
+				"\thandle_path /admin/* {\n" +
+				"\t\t@access_0_route {\n" +
+				"\t\t\tpath /users /users/*\n" +
+				"\t\t\tremote_ip 192.0.2.0/24\n" +
+				"\t\t}\n" +
+				"\t\trespond @access_0_route \"Akses ditolak\" 403\n" +
+				"\t\treverse_proxy localhost:3000\n" +
+				"\t}\n" +
+				"\thandle_path /administrator/* {\n" +
+				"\t\treverse_proxy localhost:3001\n" +
+				"\t}\n" +
+				"\thandle_path /blog/* {\n" +
+				"\t\treverse_proxy localhost:4000\n" +
+				"\t}\n" +
+				"\thandle /api/* {\n" +
+				"\t\trespond @access_0 \"Akses ditolak\" 403\n" +
+				"\t\treverse_proxy localhost:5000\n" +
+				"\t}\n" +
+				"\thandle_path /* {\n" +
+				"\t\t@access_0_route {\n" +
+				"\t\t\tpath /admin/users /admin/users/*\n" +
+				"\t\t\tremote_ip 192.0.2.0/24\n" +
+				"\t\t}\n" +
+				"\t\trespond @access_0_route \"Akses ditolak\" 403\n" +
+				"\t\treverse_proxy localhost:6000\n" +
+				"\t}\n" +
+				"\tfile_server\n" +
+				"}\n",
+		},
+		{
+			name:  "no rules",
+			rules: []AccessRule{},
+			want:  src,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := writeAccess(t, src, tt.rules)
+			if got != tt.want {
+				t.Fatalf("String() =\n%s\nwant\n%s", got, tt.want)
+			}
+
+			// Menulis ulang aturan yang dibaca kembali tidak mengubah konfigurasi
+			file, err := caddyfile.Parse(got)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if again := writeAccess(t, got, parseAccessRules(file.Site())); again != got {
+				t.Errorf("rewrite =\n%s\nwant\n%s", again, got)
+			}
+		})
+	}
+}
+
+func TestApplyAccessToRoutesRemovesRules(t *testing.T) {
+	const src = "example.com {\n" +
+		"\t@access_0 {\n" +
+		"\t\tnot remote_ip 10.0.0.0/8\n" +
+		"\t}\n" +
+		"\trespond @access_0 \"Akses ditolak\" 403\n" +
+		"\thandle_path /admin/* {\n" +
+		"\t\trespond @access_0 \"Akses ditolak\" 403\n" +
+		"\t\treverse_proxy localhost:3000\n" +
+		"\t}\n" +
+		"\treverse_proxy localhost:8080\n" +
+		"}\n"
+	const want = "example.com {\n" +
+		"\thandle_path /admin/* {\n" +
+		"\t\treverse_proxy localhost:3000\n" +
+		"\t}\n" +
+		"\treverse_proxy localhost:8080\n" +
+		"}\n"
+
+	if got := writeAccess(t, src, nil); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+}
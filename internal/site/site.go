@@ -8,11 +8,12 @@ import (
 	"strings"
 
 	"github.com/doko89/webpanel/pkg/caddy"
+	"github.com/doko89/webpanel/pkg/caddyfile"
 )
 
 var (
-	sitesDir      = "/var/www"
-	siteConfigDir = "/etc/caddy/sites"
+	sitesDir      = "/apps/sites"
+	siteConfigDir = "/etc/caddy/sites.d"
 )
 
 // Add creates a new site with the given domain name
@@ -24,37 +25,88 @@ func Add(domain string) {
 		return
 	}
 
-	// Buat direktori situs
-	siteDir := filepath.Join(sitesDir, domain)
-	if err := os.MkdirAll(siteDir, 0755); err != nil {
-		fmt.Printf("Error: Tidak dapat membuat direktori situs: %s\n", err)
+	if err := Create(domain, "", nil); err != nil {
+		fmt.Printf("Error: %s\n", err)
 		return
 	}
 
-	// Buat file konfigurasi Caddy
-	configContent := fmt.Sprintf(`%s {
-	root * %s
-	file_server
+	// Muat ulang Caddy
+	if err := caddy.Reload(); err != nil {
+		fmt.Printf("Peringatan: Tidak dapat memuat ulang Caddy: %s\n", err)
+	}
+
+	fmt.Printf("Situs %s berhasil dibuat\n", domain)
 }
-`, domain, siteDir)
+
+// Create writes the directory and Caddy configuration of a new site without
+// reloading Caddy. An empty root uses the default site directory.
+func Create(domain, root string, modules []string) error {
+	if !isValidDomain(domain) {
+		return fmt.Errorf("domain tidak valid: %s", domain)
+	}
+
+	// Root khusus yang sudah ada, misalnya docroot nginx atau Apache yang
+	// dipertahankan, dipakai apa adanya tanpa mengubah kepemilikannya
+	existingRoot := false
+	if root == "" {
+		root = filepath.Join(sitesDir, domain)
+	} else if info, err := os.Stat(root); err == nil {
+		if !info.IsDir() {
+			return fmt.Errorf("root situs bukan direktori: %s", root)
+		}
+		existingRoot = true
+	}
+
+	// Buat direktori situs
+	if !existingRoot {
+		if err := os.MkdirAll(root, 0755); err != nil {
+			return fmt.Errorf("tidak dapat membuat direktori situs: %w", err)
+		}
+	}
+
+	// Buat file konfigurasi Caddy
+	configContent := fmt.Sprintf("%s {\n\troot * %s\n", domain, root)
+	for _, module := range modules {
+		configContent += "\timport " + module + "\n"
+	}
+	configContent += "\tfile_server\n}\n"
 
 	configPath := filepath.Join(siteConfigDir, domain+".conf")
 	if err := ioutil.WriteFile(configPath, []byte(configContent), 0644); err != nil {
-		fmt.Printf("Error: Tidak dapat menulis file konfigurasi: %s\n", err)
-		return
+		return fmt.Errorf("tidak dapat menulis file konfigurasi: %w", err)
 	}
 
 	// Atur kepemilikan direktori
-	if err := os.Chown(siteDir, getCaddyUID(), getCaddyGID()); err != nil {
-		fmt.Printf("Peringatan: Tidak dapat mengubah kepemilikan direktori: %s\n", err)
+	if !existingRoot {
+		if err := os.Chown(root, getCaddyUID(), getCaddyGID()); err != nil {
+			fmt.Printf("Peringatan: Tidak dapat mengubah kepemilikan direktori: %s\n", err)
+		}
 	}
 
-	// Muat ulang Caddy
-	if err := caddy.Reload(); err != nil {
-		fmt.Printf("Peringatan: Tidak dapat memuat ulang Caddy: %s\n", err)
+	return nil
+}
+
+// CreateRedirect writes the configuration of a domain that only redirects to
+// another URL without reloading Caddy. The request URI is appended to target.
+func CreateRedirect(domain, target string, permanent bool) error {
+	if !isValidDomain(domain) {
+		return fmt.Errorf("domain tidak valid: %s", domain)
+	}
+	if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+		return fmt.Errorf("target pengalihan harus berupa URL: %s", target)
 	}
 
-	fmt.Printf("Situs %s berhasil dibuat\n", domain)
+	redir := "redir " + strings.TrimSuffix(target, "/") + "{uri}"
+	if permanent {
+		redir += " permanent"
+	}
+	configContent := fmt.Sprintf("%s {\n\t%s\n}\n", domain, redir)
+
+	configPath := filepath.Join(siteConfigDir, "redirect."+domain+".conf")
+	if err := ioutil.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		return fmt.Errorf("tidak dapat menulis file konfigurasi: %w", err)
+	}
+	return nil
 }
 
 // Remove removes an existing site
@@ -107,10 +159,79 @@ func List() {
 
 	fmt.Println("Situs yang dikonfigurasi:")
 	for _, file := range files {
-		if !file.IsDir() && strings.HasSuffix(file.Name(), ".conf") {
-			domain := strings.TrimSuffix(file.Name(), ".conf")
-			fmt.Println("-", domain)
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".conf") || strings.HasPrefix(file.Name(), "proxy.") {
+			continue
+		}
+
+		// Situs pengalihan ditampilkan beserta tujuannya
+		if strings.HasPrefix(file.Name(), "redirect.") {
+			domain := strings.TrimSuffix(strings.TrimPrefix(file.Name(), "redirect."), ".conf")
+			_, site, err := caddyfile.LoadSite(filepath.Join(siteConfigDir, file.Name()))
+			target := "[Target tidak ditemukan]"
+			if err == nil {
+				if redir := site.Find("redir"); redir != nil && redir.Arg(0) != "" {
+					target = redir.Arg(0)
+				}
+			}
+			fmt.Printf("- %s -> %s (redirect)\n", domain, target)
+			continue
 		}
+
+		domain := strings.TrimSuffix(file.Name(), ".conf")
+		fmt.Println("-", domain)
+	}
+}
+
+// Info displays the configuration of a site
+func Info(domain string) {
+	fmt.Printf("Site information for domain: %s\n", domain)
+	configPath := filepath.Join(siteConfigDir, domain+".conf")
+	_, site, err := caddyfile.LoadSite(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			fmt.Printf("Error: Domain tidak ditemukan: %s\n", domain)
+		} else {
+			fmt.Printf("Error: Tidak dapat membaca file konfigurasi: %s\n", err)
+		}
+		return
+	}
+
+	fmt.Printf("Domain     : %s\n", domain)
+	fmt.Printf("Konfigurasi: %s\n", configPath)
+	if root := site.Find("root"); root != nil && len(root.Tokens) > 1 {
+		args := root.Args()
+		fmt.Printf("Direktori  : %s\n", args[len(args)-1])
+	}
+
+	// Tampilkan mode TLS
+	tlsInfo := readTLSInfo(site)
+	switch tlsInfo.Mode {
+	case "cert":
+		expiry, err := certificateExpiry(tlsInfo.CertFile)
+		if err != nil {
+			fmt.Printf("TLS        : cert (%s, tidak dapat dibaca: %s)\n", tlsInfo.CertFile, err)
+		} else {
+			fmt.Printf("TLS        : cert (%s, berlaku sampai %s)\n", tlsInfo.CertFile, expiry.Format("2006-01-02"))
+		}
+	case "dns":
+		fmt.Printf("TLS        : dns (%s)\n", tlsInfo.Provider)
+	default:
+		if tlsInfo.Email != "" {
+			fmt.Printf("TLS        : %s (%s)\n", tlsInfo.Mode, tlsInfo.Email)
+		} else {
+			fmt.Printf("TLS        : %s\n", tlsInfo.Mode)
+		}
+	}
+
+	// Tampilkan modul yang diaktifkan
+	modules := []string{}
+	for _, node := range site.FindAll("import") {
+		modules = append(modules, strings.Join(node.Args(), " "))
+	}
+	if len(modules) > 0 {
+		fmt.Printf("Modul      : %s\n", strings.Join(modules, ", "))
+	} else {
+		fmt.Println("Modul      : -")
 	}
 }
 
@@ -0,0 +1,323 @@
+package site
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/doko89/webpanel/pkg/caddy"
+	"github.com/doko89/webpanel/pkg/caddyfile"
+)
+
+const (
+	certDir = "/etc/caddy/certs"
+)
+
+// dnsProviders memetakan penyedia DNS yang didukung ke kredensial yang dibaca Caddy dari environment
+var dnsProviders = map[string]string{
+	"cloudflare":   "{env.CF_API_TOKEN}",
+	"digitalocean": "{env.DO_AUTH_TOKEN}",
+	"duckdns":      "{env.DUCKDNS_API_TOKEN}",
+	"gandi":        "{env.GANDI_BEARER_TOKEN}",
+	"hetzner":      "{env.HETZNER_API_TOKEN}",
+	"route53":      "",
+}
+
+// TLSInfo describes the TLS configuration of a site
+type TLSInfo struct {
+	Mode     string // auto, internal, cert atau dns
+	Email    string
+	CertFile string
+	KeyFile  string
+	Provider string
+}
+
+// SetTLS changes the TLS mode of a site
+func SetTLS(domain, mode string, args []string) {
+	fmt.Printf("Setting TLS mode %s for domain: %s\n", mode, domain)
+	// Validasi domain
+	if !isValidDomain(domain) {
+		fmt.Printf("Error: Domain tidak valid: %s\n", domain)
+		return
+	}
+
+	// Situs pengalihan juga melayani HTTPS sehingga semua jenis situs dapat diubah
+	config, ok := caddy.FindSiteConfig(domain)
+	if !ok {
+		fmt.Printf("Error: Domain tidak ditemukan: %s\n", domain)
+		return
+	}
+	configPath := config.Path
+	file, site, err := caddyfile.LoadSite(configPath)
+	if err != nil {
+		fmt.Printf("Error: Tidak dapat membaca file konfigurasi: %s\n", err)
+		return
+	}
+
+	// Buat direktif tls sesuai mode
+	var directive []*caddyfile.Node
+	var restoreCert func() error
+	switch mode {
+	case "auto":
+		if len(args) > 0 {
+			if !strings.Contains(args[0], "@") {
+				fmt.Printf("Error: Email tidak valid: %s\n", args[0])
+				return
+			}
+			directive = []*caddyfile.Node{caddyfile.New("tls", args[0])}
+		}
+	case "internal":
+		directive = []*caddyfile.Node{caddyfile.New("tls", "internal")}
+	case "cert":
+		if len(args) < 2 {
+			fmt.Println("Error: File sertifikat dan kunci diperlukan")
+			return
+		}
+		certFile, keyFile, restore, err := importCertificate(domain, args[0], args[1])
+		if err != nil {
+			fmt.Printf("Error: %s\n", err)
+			return
+		}
+		restoreCert = restore
+		directive = []*caddyfile.Node{caddyfile.New("tls", certFile, keyFile)}
+	case "dns":
+		if len(args) < 1 {
+			fmt.Println("Error: Penyedia DNS diperlukan")
+			return
+		}
+		provider := args[0]
+		credential, ok := dnsProviders[provider]
+		if !ok {
+			fmt.Printf("Error: Penyedia DNS tidak didukung: %s\n", provider)
+			fmt.Printf("Penyedia yang didukung: %s\n", strings.Join(supportedDNSProviders(), ", "))
+			return
+		}
+		dns := caddyfile.New("dns", provider)
+		if credential != "" {
+			dns = caddyfile.New("dns", provider, credential)
+		}
+		directive = []*caddyfile.Node{caddyfile.New("tls").Add(dns)}
+	default:
+		fmt.Printf("Error: Mode TLS tidak valid: %s (harus auto, internal, cert atau dns)\n", mode)
+		return
+	}
+
+	// Sertifikat wildcard hanya dapat diterbitkan melalui tantangan DNS
+	if strings.HasPrefix(domain, "*.") && mode == "auto" {
+		fmt.Println("Peringatan: Domain wildcard memerlukan mode dns, cert atau internal")
+	}
+
+	// Tulis secara atomik dan validasi, konfigurasi lama dipulihkan jika tidak valid
+	site.Set("tls", directive...)
+	if err := caddy.ReplaceConfig(configPath, []byte(file.String())); err != nil {
+		fmt.Printf("Error: Perubahan dibatalkan: %s\n", err)
+		// Sertifikat yang sudah disalin juga dikembalikan agar tetap cocok dengan konfigurasi lama
+		if restoreCert != nil {
+			if err := restoreCert(); err != nil {
+				fmt.Printf("Peringatan: Sertifikat lama tidak dapat dipulihkan: %s\n", err)
+			}
+		}
+		return
+	}
+
+	// Muat ulang Caddy
+	if err := caddy.Reload(); err != nil {
+		fmt.Printf("Peringatan: Tidak dapat memuat ulang Caddy: %s\n", err)
+	}
+
+	fmt.Printf("Mode TLS %s berhasil diterapkan untuk %s\n", mode, domain)
+	if mode == "dns" && dnsProviders[args[0]] != "" {
+		fmt.Printf("Catatan: Pastikan Caddy memiliki plugin dns.providers.%s dan variabel %s tersedia di environment layanan Caddy\n",
+			args[0], strings.TrimSuffix(strings.TrimPrefix(dnsProviders[args[0]], "{env."), "}"))
+	}
+}
+
+// importCertificate memvalidasi pasangan sertifikat dan kunci lalu menyalinnya ke
+// direktori sertifikat; fungsi restore mengembalikan file lama jika konfigurasi ditolak
+func importCertificate(domain, certPath, keyPath string) (string, string, func() error, error) {
+	certPEM, err := ioutil.ReadFile(certPath)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("tidak dapat membaca sertifikat: %w", err)
+	}
+	keyPEM, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("tidak dapat membaca kunci: %w", err)
+	}
+
+	// X509KeyPair juga memastikan kunci privat cocok dengan sertifikat
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("sertifikat dan kunci tidak cocok: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return "", "", nil, fmt.Errorf("tidak dapat mengurai sertifikat: %w", err)
+	}
+
+	now := time.Now()
+	if now.Before(leaf.NotBefore) {
+		return "", "", nil, fmt.Errorf("sertifikat belum berlaku sampai %s", leaf.NotBefore.Format("2006-01-02"))
+	}
+	if now.After(leaf.NotAfter) {
+		return "", "", nil, fmt.Errorf("sertifikat sudah kedaluwarsa pada %s", leaf.NotAfter.Format("2006-01-02"))
+	}
+	// Domain wildcard diperiksa menggunakan contoh subdomain
+	hostname := domain
+	if strings.HasPrefix(hostname, "*.") {
+		hostname = "www" + hostname[1:]
+	}
+	if err := leaf.VerifyHostname(hostname); err != nil {
+		return "", "", nil, fmt.Errorf("sertifikat tidak berlaku untuk %s: %w", domain, err)
+	}
+	if leaf.NotAfter.Sub(now) < 30*24*time.Hour {
+		fmt.Printf("Peringatan: Sertifikat akan kedaluwarsa pada %s\n", leaf.NotAfter.Format("2006-01-02"))
+	}
+
+	// Salin sertifikat ke direktori yang dapat dibaca Caddy
+	dir := filepath.Join(certDir, domain)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return "", "", nil, fmt.Errorf("tidak dapat membuat direktori sertifikat: %w", err)
+	}
+	if err := os.Chown(dir, getCaddyUID(), getCaddyGID()); err != nil {
+		fmt.Printf("Peringatan: Tidak dapat mengubah kepemilikan %s: %s\n", dir, err)
+	}
+	files := []pemFile{
+		{path: filepath.Join(dir, "cert.pem"), data: certPEM, perm: 0644},
+		{path: filepath.Join(dir, "key.pem"), data: keyPEM, perm: 0640},
+	}
+	restore, err := installCertFiles(files)
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	return files[0].path, files[1].path, restore, nil
+}
+
+// pemFile adalah file sertifikat atau kunci yang dipasang ke direktori sertifikat
+type pemFile struct {
+	path string
+	data []byte
+	perm os.FileMode
+}
+
+// installCertFiles menulis file sertifikat secara atomik dan mengembalikan
+// fungsi yang memulihkan isi lamanya jika konfigurasi baru ditolak
+func installCertFiles(files []pemFile) (func() error, error) {
+	previous := make([][]byte, len(files))
+	for i, file := range files {
+		content, err := ioutil.ReadFile(file.path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("tidak dapat membaca %s: %w", file.path, err)
+		}
+		// nil menandai file baru yang dihapus saat pemulihan
+		previous[i] = content
+	}
+
+	written := 0
+	restore := func() error {
+		var firstErr error
+		for i := 0; i < written; i++ {
+			old := pemFile{path: files[i].path, data: previous[i], perm: files[i].perm}
+			var err error
+			if old.data == nil {
+				err = os.Remove(old.path)
+			} else {
+				err = writeCertFile(old)
+			}
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return firstErr
+	}
+
+	for _, file := range files {
+		if err := writeCertFile(file); err != nil {
+			restore()
+			return nil, fmt.Errorf("tidak dapat menyimpan %s: %w", file.path, err)
+		}
+		written++
+	}
+	return restore, nil
+}
+
+// writeCertFile menulis satu file sertifikat dan menyerahkannya ke pengguna caddy
+func writeCertFile(file pemFile) error {
+	if err := caddy.WriteFileAtomic(file.path, file.data, file.perm); err != nil {
+		return err
+	}
+	if err := os.Chown(file.path, getCaddyUID(), getCaddyGID()); err != nil {
+		fmt.Printf("Peringatan: Tidak dapat mengubah kepemilikan %s: %s\n", file.path, err)
+	}
+	return nil
+}
+
+// readTLSInfo membaca mode TLS dari blok situs
+func readTLSInfo(site *caddyfile.Node) TLSInfo {
+	info := TLSInfo{Mode: "auto"}
+	node := site.Find("tls")
+	if node == nil {
+		return info
+	}
+
+	args := node.Args()
+	switch {
+	case len(args) == 1 && args[0] == "internal":
+		info.Mode = "internal"
+	case len(args) >= 2:
+		info.Mode = "cert"
+		info.CertFile = args[0]
+		info.KeyFile = args[1]
+	case len(args) == 1:
+		info.Email = args[0]
+	}
+
+	// Cari penyedia DNS di dalam blok tls
+	if dns := node.Find("dns"); dns != nil && dns.Arg(0) != "" {
+		info.Mode = "dns"
+		info.Provider = dns.Arg(0)
+	}
+
+	return info
+}
+
+// certificateExpiry membaca tanggal kedaluwarsa sertifikat
+func certificateExpiry(certFile string) (time.Time, error) {
+	certPEM, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// Gunakan blok CERTIFICATE pertama sebagai sertifikat utama
+	for {
+		var block *pem.Block
+		block, certPEM = pem.Decode(certPEM)
+		if block == nil {
+			return time.Time{}, fmt.Errorf("sertifikat tidak ditemukan di %s", certFile)
+		}
+		if block.Type == "CERTIFICATE" {
+			leaf, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return leaf.NotAfter, nil
+		}
+	}
+}
+
+// supportedDNSProviders mengembalikan daftar penyedia DNS yang didukung
+func supportedDNSProviders() []string {
+	providers := []string{}
+	for name := range dnsProviders {
+		providers = append(providers, name)
+	}
+	sort.Strings(providers)
+	return providers
+}
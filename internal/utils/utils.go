@@ -6,6 +6,8 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+
+	"github.com/doko89/webpanel/internal/module"
 )
 
 // InstallDependencies installs all required dependencies
@@ -247,12 +249,12 @@ func setupDirectories() {
 	}
 
 	// Buat direktori backup
-	if err := os.MkdirAll("/backup/daily", 0755); err != nil {
-		fmt.Printf("Error: Tidak dapat membuat direktori /backup/daily: %s\n", err)
+	if err := os.MkdirAll("/backup/snapshots", 0700); err != nil {
+		fmt.Printf("Error: Tidak dapat membuat direktori /backup/snapshots: %s\n", err)
 	}
 
-	if err := os.MkdirAll("/backup/weekly", 0755); err != nil {
-		fmt.Printf("Error: Tidak dapat membuat direktori /backup/weekly: %s\n", err)
+	if err := os.MkdirAll("/backup/databases", 0700); err != nil {
+		fmt.Printf("Error: Tidak dapat membuat direktori /backup/databases: %s\n", err)
 	}
 
 	// Buat Caddyfile utama
@@ -273,93 +275,31 @@ import sites.d/*.conf
 	fmt.Println("Direktori berhasil dibuat")
 }
 
-// setupDefaultModules membuat modul default
+// setupDefaultModules memasang modul bawaan yang belum ada tanpa menimpa
+// modul yang sudah diubah secara lokal
 func setupDefaultModules() {
 	fmt.Println("\nMembuat modul default...")
 
-	modules := map[string]string{
-		"spa": `(spa) {
-	@spa {
-		not path *.php
-		not path /api/*
-		not path *.js
-		not path *.css
-		not path *.png
-		not path *.jpg
-		not path *.jpeg
-		not path *.svg
-		not path *.gif
-		not path *.ico
-		not path *.woff
-		not path *.woff2
-		not path *.ttf
-		not path *.eot
-		file {
-			try_files {path} /index.html
-		}
-	}
-	rewrite @spa /index.html
-}`,
-		"security": `(security) {
-	header {
-		# Keamanan dasar
-		X-XSS-Protection "1; mode=block"
-		X-Content-Type-Options "nosniff"
-		X-Frame-Options "SAMEORIGIN"
-		Referrer-Policy "strict-origin-when-cross-origin"
-		
-		# Hapus header yang tidak perlu
-		-Server
-		-X-Powered-By
-	}
-}`,
-		"ratelimit": `(ratelimit) {
-	rate_limit {
-		zone dynamic {
-			key {remote_host}
-			events 100
-			window 10s
-		}
-	}
-}`,
-		"compression": `(compression) {
-	encode gzip zstd
-}`,
-		"cache-headers": `(cache-headers) {
-	@static {
-		path *.css *.js *.png *.jpg *.jpeg *.gif *.ico *.svg *.woff *.woff2 *.ttf *.eot
-	}
-	header @static Cache-Control "public, max-age=31536000"
-}`,
-		"local-access": `(local-access) {
-	@local {
-		remote_ip 127.0.0.1 192.168.0.0/16 10.0.0.0/8 172.16.0.0/12
-	}
-	@notLocal {
-		not remote_ip 127.0.0.1 192.168.0.0/16 10.0.0.0/8 172.16.0.0/12
-	}
-	handle @notLocal {
-		respond "Akses ditolak" 403
-	}
-}`,
+	installed, err := module.InstallDefaults()
+	if err != nil {
+		fmt.Printf("Error: Tidak dapat memasang modul default: %s\n", err)
+		return
 	}
-
-	for name, content := range modules {
-		modulePath := fmt.Sprintf("/etc/caddy/module.d/%s", name)
-		if err := os.WriteFile(modulePath, []byte(content), 0644); err != nil {
-			fmt.Printf("Error: Tidak dapat menulis modul %s: %s\n", name, err)
-		}
+	if len(installed) > 0 {
+		fmt.Printf("Modul default dipasang: %s\n", strings.Join(installed, ", "))
 	}
 
-	fmt.Println("Modul default berhasil dibuat")
+	fmt.Println("Modul default berhasil dibuat, gunakan 'webpanel module upgrade' untuk memperbarui modul yang sudah ada")
 }
 
 // createPhpModule membuat modul Caddy untuk PHP
 func createPhpModule(version string) {
-	moduleContent := fmt.Sprintf(`(php%s) {
+	moduleContent := fmt.Sprintf(`# description Menjalankan PHP %s melalui php-fpm
+# weight 90
+(php%s) {
 	php_fastcgi unix//run/php/php%s-fpm.sock
 }
-`, version, version)
+`, version, version, version)
 
 	modulePath := fmt.Sprintf("/etc/caddy/module.d/php%s", version)
 	if err := os.WriteFile(modulePath, []byte(moduleContent), 0644); err != nil {
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"os/user"
+	"strings"
 
 	"github.com/doko89/webpanel/internal/backup"
+	"github.com/doko89/webpanel/internal/bundle"
 	"github.com/doko89/webpanel/internal/database"
+	"github.com/doko89/webpanel/internal/importer"
 	"github.com/doko89/webpanel/internal/module"
 	"github.com/doko89/webpanel/internal/php"
 	"github.com/doko89/webpanel/internal/proxy"
@@ -15,7 +19,10 @@ import (
 )
 
 func main() {
-	fmt.Println("WebPanel CLI - Server Administration Tool")
+	// Keluaran JSON harus dapat langsung diurai, jangan cetak judul
+	if !hasArg(os.Args[1:], "--json") {
+		fmt.Println("WebPanel CLI - Server Administration Tool")
+	}
 
 	// Periksa apakah berjalan sebagai root
 	currentUser, err := user.Current()
@@ -52,6 +59,8 @@ func main() {
 		handleDatabaseCommand(args)
 	case "php":
 		handlePHPCommand(args)
+	case "import":
+		handleImportCommand(args)
 	case "install":
 		handleInstallCommand(args)
 	case "help":
@@ -88,6 +97,47 @@ func handleSiteCommand(args []string) {
 		site.Remove(args[1])
 	case "list":
 		site.List()
+	case "info":
+		if len(args) < 2 {
+			fmt.Println("Error: Domain diperlukan")
+			printSiteHelp()
+			os.Exit(1)
+		}
+		site.Info(args[1])
+	case "tls":
+		if len(args) < 4 || args[1] != "set" {
+			fmt.Println("Error: Domain dan mode TLS diperlukan")
+			printSiteHelp()
+			os.Exit(1)
+		}
+		site.SetTLS(args[2], args[3], args[4:])
+	case "access":
+		handleSiteAccessCommand(args[1:])
+	case "export":
+		fs := flag.NewFlagSet("site export", flag.ExitOnError)
+		output := fs.String("o", "", "File bundle tujuan")
+		var databases stringList
+		fs.Var(&databases, "db", "Database yang disertakan (boleh diulang)")
+		positional := parseFlags(fs, args[1:])
+		if len(positional) < 1 {
+			fmt.Println("Error: Domain diperlukan")
+			printSiteHelp()
+			os.Exit(1)
+		}
+		if *output == "" {
+			*output = positional[0] + ".tar.gz"
+		}
+		bundle.Export(positional[0], *output, databases)
+	case "import":
+		fs := flag.NewFlagSet("site import", flag.ExitOnError)
+		domain := fs.String("domain", "", "Domain baru untuk situs yang diimpor")
+		positional := parseFlags(fs, args[1:])
+		if len(positional) < 1 {
+			fmt.Println("Error: File bundle diperlukan")
+			printSiteHelp()
+			os.Exit(1)
+		}
+		bundle.Import(positional[0], *domain)
 	default:
 		fmt.Printf("Error: Subperintah site tidak dikenal: %s\n", subcommand)
 		printSiteHelp()
@@ -95,6 +145,47 @@ func handleSiteCommand(args []string) {
 	}
 }
 
+func handleSiteAccessCommand(args []string) {
+	if len(args) < 1 {
+		fmt.Println("Error: Subperintah access diperlukan")
+		printSiteHelp()
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet("site access", flag.ExitOnError)
+	path := fs.String("path", "", "Path yang dibatasi, misalnya /admin")
+	positional := parseFlags(fs, args[1:])
+
+	subcommand := args[0]
+	switch subcommand {
+	case "allow", "deny", "remove":
+		if len(positional) < 2 {
+			fmt.Println("Error: Domain dan CIDR diperlukan")
+			printSiteHelp()
+			os.Exit(1)
+		}
+		switch subcommand {
+		case "allow":
+			site.AllowAccess(positional[0], positional[1], *path)
+		case "deny":
+			site.DenyAccess(positional[0], positional[1], *path)
+		default:
+			site.RemoveAccess(positional[0], positional[1], *path)
+		}
+	case "list":
+		if len(positional) < 1 {
+			fmt.Println("Error: Domain diperlukan")
+			printSiteHelp()
+			os.Exit(1)
+		}
+		site.ListAccess(positional[0])
+	default:
+		fmt.Printf("Error: Subperintah access tidak dikenal: %s\n", subcommand)
+		printSiteHelp()
+		os.Exit(1)
+	}
+}
+
 func handleProxyCommand(args []string) {
 	if len(args) < 1 {
 		fmt.Println("Error: Subperintah proxy diperlukan")
@@ -110,7 +201,60 @@ func handleProxyCommand(args []string) {
 			printProxyHelp()
 			os.Exit(1)
 		}
-		proxy.Add(args[1], args[2])
+		fs := flag.NewFlagSet("proxy add", flag.ExitOnError)
+		lbPolicy := fs.String("lb", "", "Kebijakan load balancing")
+		healthURI := fs.String("health-uri", "", "URI pemeriksaan kesehatan upstream")
+		healthInterval := fs.String("health-interval", "", "Interval pemeriksaan kesehatan")
+		hostHeader := fs.String("host-header", "", "Nilai header Host ke upstream")
+		var headerUp, headerDown stringList
+		fs.Var(&headerUp, "header-up", "Header ke upstream, \"Nama nilai\" (boleh diulang)")
+		fs.Var(&headerDown, "header-down", "Header ke klien, \"Nama nilai\" (boleh diulang)")
+		dialTimeout := fs.String("dial-timeout", "", "Batas waktu koneksi ke upstream")
+		readTimeout := fs.String("read-timeout", "", "Batas waktu membaca dari upstream")
+		writeTimeout := fs.String("write-timeout", "", "Batas waktu menulis ke upstream")
+		noBuffering := fs.Bool("no-buffering", false, "Nonaktifkan buffering respons")
+		tlsSkipVerify := fs.Bool("tls-skip-verify", false, "Lewati verifikasi sertifikat upstream HTTPS")
+		maxBodySize := fs.String("max-body-size", "", "Ukuran maksimum body permintaan")
+		noCheck := fs.Bool("no-check", false, "Lewati pemeriksaan koneksi ke target")
+		var sockets stringList
+		fs.Var(&sockets, "socket", "Path socket Unix sebagai target (boleh diulang)")
+		positional := parseFlags(fs, args[1:])
+		if len(positional) < 1 || len(positional)+len(sockets) < 2 {
+			fmt.Println("Error: Domain dan target diperlukan")
+			printProxyHelp()
+			os.Exit(1)
+		}
+		upstreams := positional[1:]
+		for _, socket := range sockets {
+			upstreams = append(upstreams, proxy.SocketTarget(socket))
+		}
+		cfg := proxy.Config{
+			Domain:         positional[0],
+			Upstreams:      upstreams,
+			LBPolicy:       *lbPolicy,
+			HealthURI:      *healthURI,
+			HealthInterval: *healthInterval,
+			HostHeader:     *hostHeader,
+			DialTimeout:    *dialTimeout,
+			ReadTimeout:    *readTimeout,
+			WriteTimeout:   *writeTimeout,
+			NoBuffering:    *noBuffering,
+			TLSSkipVerify:  *tlsSkipVerify,
+			MaxBodySize:    *maxBodySize,
+		}
+		for _, header := range headerUp {
+			if err := cfg.Set("header-up", header); err != nil {
+				fmt.Printf("Error: %s\n", err)
+				os.Exit(1)
+			}
+		}
+		for _, header := range headerDown {
+			if err := cfg.Set("header-down", header); err != nil {
+				fmt.Printf("Error: %s\n", err)
+				os.Exit(1)
+			}
+		}
+		proxy.Add(cfg, !*noCheck)
 	case "remove":
 		if len(args) < 2 {
 			fmt.Println("Error: Domain diperlukan")
@@ -120,6 +264,81 @@ func handleProxyCommand(args []string) {
 		proxy.Remove(args[1])
 	case "list":
 		proxy.List()
+	case "update":
+		fs := flag.NewFlagSet("proxy update", flag.ExitOnError)
+		var targets, headerUp, headerDown stringList
+		fs.Var(&targets, "target", "Target baru (boleh diulang untuk beberapa upstream)")
+		fs.Var(&headerUp, "header-up", "Header ke upstream, \"Nama nilai\" (boleh diulang)")
+		fs.Var(&headerDown, "header-down", "Header ke klien, \"Nama nilai\" (boleh diulang)")
+		for _, name := range proxy.Options {
+			if name != "header-up" && name != "header-down" {
+				fs.String(name, "", "Nilai baru untuk opsi "+name)
+			}
+		}
+		noCheck := fs.Bool("no-check", false, "Lewati pemeriksaan koneksi ke target baru")
+		var sockets stringList
+		fs.Var(&sockets, "socket", "Path socket Unix sebagai target baru (boleh diulang)")
+		positional := parseFlags(fs, args[1:])
+		if len(positional) < 1 {
+			fmt.Println("Error: Domain diperlukan")
+			printProxyHelp()
+			os.Exit(1)
+		}
+
+		// Hanya opsi yang diberikan di baris perintah yang diubah
+		options := []proxy.Option{}
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name != "target" && f.Name != "socket" && f.Name != "header-up" && f.Name != "header-down" && f.Name != "no-check" {
+				options = append(options, proxy.Option{Name: f.Name, Value: f.Value.String()})
+			}
+		})
+		for _, socket := range sockets {
+			targets = append(targets, proxy.SocketTarget(socket))
+		}
+		for _, header := range headerUp {
+			options = append(options, proxy.Option{Name: "header-up", Value: header})
+		}
+		for _, header := range headerDown {
+			options = append(options, proxy.Option{Name: "header-down", Value: header})
+		}
+		proxy.Update(positional[0], targets, options, !*noCheck)
+	case "info":
+		if len(args) < 2 {
+			fmt.Println("Error: Domain diperlukan")
+			printProxyHelp()
+			os.Exit(1)
+		}
+		proxy.Info(args[1])
+	case "set":
+		if len(args) < 4 {
+			fmt.Println("Error: Domain, opsi dan nilai diperlukan")
+			printProxyHelp()
+			os.Exit(1)
+		}
+		proxy.SetOption(args[1], args[2], strings.Join(args[3:], " "))
+	case "route":
+		handleProxyRouteCommand(args[1:])
+	case "service":
+		handleProxyServiceCommand(args[1:])
+	case "upstream":
+		fs := flag.NewFlagSet("proxy upstream", flag.ExitOnError)
+		noCheck := fs.Bool("no-check", false, "Lewati pemeriksaan koneksi ke target")
+		positional := parseFlags(fs, args[1:])
+		if len(positional) < 3 {
+			fmt.Println("Error: Subperintah upstream, domain dan target diperlukan")
+			printProxyHelp()
+			os.Exit(1)
+		}
+		switch positional[0] {
+		case "add":
+			proxy.AddUpstream(positional[1], positional[2], !*noCheck)
+		case "remove":
+			proxy.RemoveUpstream(positional[1], positional[2])
+		default:
+			fmt.Printf("Error: Subperintah upstream tidak dikenal: %s\n", positional[0])
+			printProxyHelp()
+			os.Exit(1)
+		}
 	default:
 		fmt.Printf("Error: Subperintah proxy tidak dikenal: %s\n", subcommand)
 		printProxyHelp()
@@ -127,6 +346,123 @@ func handleProxyCommand(args []string) {
 	}
 }
 
+func handleProxyRouteCommand(args []string) {
+	if len(args) < 1 {
+		fmt.Println("Error: Subperintah route diperlukan")
+		printProxyHelp()
+		os.Exit(1)
+	}
+
+	subcommand := args[0]
+	switch subcommand {
+	case "add":
+		fs := flag.NewFlagSet("proxy route add", flag.ExitOnError)
+		stripPrefix := fs.Bool("strip-prefix", false, "Hapus prefix path sebelum diteruskan")
+		noCheck := fs.Bool("no-check", false, "Lewati pemeriksaan koneksi ke target")
+		positional := parseFlags(fs, args[1:])
+		if len(positional) < 3 {
+			fmt.Println("Error: Domain, path dan target diperlukan")
+			printProxyHelp()
+			os.Exit(1)
+		}
+		proxy.AddRoute(positional[0], positional[1], positional[2], *stripPrefix, !*noCheck)
+	case "remove":
+		if len(args) < 3 {
+			fmt.Println("Error: Domain dan path diperlukan")
+			printProxyHelp()
+			os.Exit(1)
+		}
+		proxy.RemoveRoute(args[1], args[2])
+	case "list":
+		if len(args) < 2 {
+			fmt.Println("Error: Domain diperlukan")
+			printProxyHelp()
+			os.Exit(1)
+		}
+		proxy.ListRoutes(args[1])
+	default:
+		fmt.Printf("Error: Subperintah route tidak dikenal: %s\n", subcommand)
+		printProxyHelp()
+		os.Exit(1)
+	}
+}
+
+func handleProxyServiceCommand(args []string) {
+	if len(args) < 1 {
+		fmt.Println("Error: Subperintah service diperlukan")
+		printProxyHelp()
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet("proxy service", flag.ExitOnError)
+	unit := fs.String("unit", "", "Unit systemd yang menjalankan layanan")
+	noCheck := fs.Bool("no-check", false, "Lewati pemeriksaan koneksi ke target")
+	positional := parseFlags(fs, args[1:])
+
+	subcommand := args[0]
+	switch subcommand {
+	case "add", "set":
+		if len(positional) < 2 {
+			fmt.Println("Error: Nama layanan dan target diperlukan")
+			printProxyHelp()
+			os.Exit(1)
+		}
+		if subcommand == "add" {
+			proxy.AddService(positional[0], positional[1], *unit, !*noCheck)
+		} else {
+			proxy.SetService(positional[0], positional[1], !*noCheck)
+		}
+	case "remove":
+		if len(positional) < 1 {
+			fmt.Println("Error: Nama layanan diperlukan")
+			printProxyHelp()
+			os.Exit(1)
+		}
+		proxy.RemoveService(positional[0])
+	case "list":
+		proxy.ListServices()
+	default:
+		fmt.Printf("Error: Subperintah service tidak dikenal: %s\n", subcommand)
+		printProxyHelp()
+		os.Exit(1)
+	}
+}
+
+// moduleSelectorFlags mendaftarkan flag pemilihan situs untuk operasi modul massal
+func moduleSelectorFlags(fs *flag.FlagSet) *module.Selector {
+	selector := &module.Selector{}
+	fs.BoolVar(&selector.All, "all", false, "Terapkan ke semua situs")
+	fs.StringVar(&selector.Type, "type", "", "Terapkan ke situs dengan tipe tertentu ("+strings.Join(module.SiteTypes, ", ")+")")
+	fs.StringVar(&selector.DomainsFrom, "domains-from", "", "Terapkan ke domain di file, satu domain per baris")
+	return selector
+}
+
+// checkModuleSelector memeriksa argumen enable/disable dan mengembalikan true
+// untuk operasi massal
+func checkModuleSelector(selector module.Selector, positional []string) bool {
+	selected := 0
+	for _, set := range []bool{selector.All, selector.Type != "", selector.DomainsFrom != ""} {
+		if set {
+			selected++
+		}
+	}
+	switch {
+	case selected > 1:
+		fmt.Println("Error: Gunakan hanya salah satu dari --all, --type atau --domains-from")
+	case selected == 1 && len(positional) == 1:
+		return true
+	case selected == 1 && len(positional) > 1:
+		fmt.Println("Error: Domain tidak dapat digunakan bersama --all, --type atau --domains-from")
+	case selected == 0 && len(positional) >= 2:
+		return false
+	default:
+		fmt.Println("Error: Nama modul dan domain diperlukan")
+	}
+	printModuleHelp()
+	os.Exit(1)
+	return false
+}
+
 func handleModuleCommand(args []string) {
 	if len(args) < 1 {
 		fmt.Println("Error: Subperintah module diperlukan")
@@ -137,19 +473,25 @@ func handleModuleCommand(args []string) {
 	subcommand := args[0]
 	switch subcommand {
 	case "enable":
-		if len(args) < 3 {
-			fmt.Println("Error: Nama modul dan domain diperlukan")
-			printModuleHelp()
-			os.Exit(1)
+		fs := flag.NewFlagSet("module enable", flag.ExitOnError)
+		var params stringList
+		fs.Var(&params, "param", "Parameter modul nama=nilai (boleh diulang)")
+		selector := moduleSelectorFlags(fs)
+		positional := parseFlags(fs, args[1:])
+		if bulk := checkModuleSelector(*selector, positional); bulk {
+			module.EnableMany(positional[0], *selector, params)
+			return
 		}
-		module.Enable(args[1], args[2])
+		module.Enable(positional[0], positional[1], params)
 	case "disable":
-		if len(args) < 3 {
-			fmt.Println("Error: Nama modul dan domain diperlukan")
-			printModuleHelp()
-			os.Exit(1)
+		fs := flag.NewFlagSet("module disable", flag.ExitOnError)
+		selector := moduleSelectorFlags(fs)
+		positional := parseFlags(fs, args[1:])
+		if bulk := checkModuleSelector(*selector, positional); bulk {
+			module.DisableMany(positional[0], *selector)
+			return
 		}
-		module.Disable(args[1], args[2])
+		module.Disable(positional[0], positional[1])
 	case "list":
 		if len(args) < 2 {
 			fmt.Println("Error: Domain diperlukan")
@@ -159,6 +501,54 @@ func handleModuleCommand(args []string) {
 		module.List(args[1])
 	case "list-available":
 		module.ListAvailable()
+	case "create":
+		fs := flag.NewFlagSet("module create", flag.ExitOnError)
+		fromFile := fs.String("from-file", "", "File berisi snippet modul")
+		positional := parseFlags(fs, args[1:])
+		if len(positional) < 1 || *fromFile == "" {
+			fmt.Println("Error: Nama modul dan --from-file diperlukan")
+			printModuleHelp()
+			os.Exit(1)
+		}
+		module.Create(positional[0], *fromFile)
+	case "edit":
+		if len(args) < 2 {
+			fmt.Println("Error: Nama modul diperlukan")
+			printModuleHelp()
+			os.Exit(1)
+		}
+		module.Edit(args[1])
+	case "delete":
+		if len(args) < 2 {
+			fmt.Println("Error: Nama modul diperlukan")
+			printModuleHelp()
+			os.Exit(1)
+		}
+		module.Delete(args[1])
+	case "show":
+		if len(args) < 2 {
+			fmt.Println("Error: Nama modul diperlukan")
+			printModuleHelp()
+			os.Exit(1)
+		}
+		module.Show(args[1])
+	case "usage":
+		module.Usage()
+	case "plugins":
+		module.Plugins()
+	case "build-caddy":
+		fs := flag.NewFlagSet("module build-caddy", flag.ExitOnError)
+		output := fs.String("output", "caddy", "Path binary Caddy yang dihasilkan")
+		online := fs.Bool("online", false, "Izinkan mengunduh modul Go yang tidak ada di cache lokal")
+		var with stringList
+		fs.Var(&with, "with", "Paket Go plugin tambahan (boleh diulang)")
+		parseFlags(fs, args[1:])
+		module.BuildCaddy(*output, with, *online)
+	case "upgrade":
+		fs := flag.NewFlagSet("module upgrade", flag.ExitOnError)
+		keepModified := fs.Bool("keep-modified", false, "Pertahankan modul yang diubah secara lokal tanpa bertanya")
+		parseFlags(fs, args[1:])
+		module.Upgrade(*keepModified)
 	default:
 		fmt.Printf("Error: Subperintah module tidak dikenal: %s\n", subcommand)
 		printModuleHelp()
@@ -176,12 +566,16 @@ func handleBackupCommand(args []string) {
 	subcommand := args[0]
 	switch subcommand {
 	case "enable":
-		if len(args) < 3 {
+		fs := flag.NewFlagSet("backup enable", flag.ExitOnError)
+		options := backupScheduleFlags(fs)
+		fs.DurationVar(&options.Jitter, "jitter", 0, "Penundaan acak maksimal sebelum backup dimulai, misalnya 30m")
+		positional := parseFlags(fs, args[1:])
+		if len(positional) < 2 {
 			fmt.Println("Error: Jenis backup dan domain diperlukan")
 			printBackupHelp()
 			os.Exit(1)
 		}
-		backup.Enable(args[1], args[2])
+		backup.Enable(positional[0], positional[1], *options)
 	case "disable":
 		if len(args) < 3 {
 			fmt.Println("Error: Jenis backup dan domain diperlukan")
@@ -189,14 +583,101 @@ func handleBackupCommand(args []string) {
 			os.Exit(1)
 		}
 		backup.Disable(args[1], args[2])
+	case "run":
+		fs := flag.NewFlagSet("backup run", flag.ExitOnError)
+		backupType := fs.String("type", "manual", "Tipe snapshot: daily, weekly atau manual")
+		jitter := fs.Duration("jitter", 0, "Penundaan acak maksimal sebelum backup dimulai")
+		format := fs.String("format", "", "Format kompresi: zst atau gz (bawaan zst jika zstd terpasang)")
+		var databases stringList
+		fs.Var(&databases, "db", "Database yang ikut dibackup (boleh diulang)")
+		positional := parseFlags(fs, args[1:])
+		if len(positional) < 1 {
+			fmt.Println("Error: Domain diperlukan")
+			printBackupHelp()
+			os.Exit(1)
+		}
+		backup.Run(positional[0], *backupType, databases, *format, *jitter)
+	case "list":
+		fs := flag.NewFlagSet("backup list", flag.ExitOnError)
+		jsonOutput := fs.Bool("json", false, "Tampilkan dalam format JSON")
+		positional := parseFlags(fs, args[1:])
+		domain := ""
+		if len(positional) > 0 {
+			domain = positional[0]
+		}
+		backup.List(domain, *jsonOutput)
+	case "status":
+		fs := flag.NewFlagSet("backup status", flag.ExitOnError)
+		jsonOutput := fs.Bool("json", false, "Tampilkan dalam format JSON")
+		parseFlags(fs, args[1:])
+		backup.Status(*jsonOutput)
+	case "prune":
+		fs := flag.NewFlagSet("backup prune", flag.ExitOnError)
+		dryRun := fs.Bool("dry-run", false, "Tampilkan snapshot yang akan dihapus tanpa menghapusnya")
+		positional := parseFlags(fs, args[1:])
+		domain := ""
+		if len(positional) > 0 {
+			domain = positional[0]
+		}
+		backup.Prune(domain, *dryRun)
+	case "retention":
+		handleBackupRetention(args[1:])
+	case "restore":
+		fs := flag.NewFlagSet("backup restore", flag.ExitOnError)
+		var options backup.RestoreOptions
+		fs.StringVar(&options.Snapshot, "snapshot", "", "ID snapshot yang dipulihkan (bawaan snapshot terbaru)")
+		fs.BoolVar(&options.FilesOnly, "files-only", false, "Hanya pulihkan file situs")
+		fs.BoolVar(&options.DBOnly, "db-only", false, "Hanya pulihkan database")
+		fs.StringVar(&options.To, "to", "", "Pulihkan ke direktori staging, bukan di tempat")
+		fs.BoolVar(&options.Safety, "safety-snapshot", false, "Buat snapshot keadaan saat ini sebelum menimpa")
+		positional := parseFlags(fs, args[1:])
+		if len(positional) < 1 {
+			fmt.Println("Error: Domain diperlukan")
+			printBackupHelp()
+			os.Exit(1)
+		}
+		backup.Restore(positional[0], options)
 	case "dbbackup":
-		if len(args) < 3 && args[1] == "add" {
-			fmt.Println("Error: Nama database diperlukan")
+		if len(args) < 2 {
+			fmt.Println("Error: Subperintah dbbackup diperlukan")
 			printBackupHelp()
 			os.Exit(1)
 		}
 		if args[1] == "add" {
-			backup.AddDBBackup(args[2])
+			fs := flag.NewFlagSet("backup dbbackup add", flag.ExitOnError)
+			options := backupScheduleFlags(fs)
+			fs.DurationVar(&options.Jitter, "jitter", 0, "Penundaan acak maksimal sebelum backup dimulai, misalnya 30m")
+			positional := parseFlags(fs, args[2:])
+			if len(positional) < 1 {
+				fmt.Println("Error: Nama database diperlukan")
+				printBackupHelp()
+				os.Exit(1)
+			}
+			backup.AddDBBackup(positional[0], *options)
+		} else if args[1] == "run" {
+			fs := flag.NewFlagSet("backup dbbackup run", flag.ExitOnError)
+			format := fs.String("format", "", "Format kompresi: zst atau gz (bawaan zst jika zstd terpasang)")
+			jitter := fs.Duration("jitter", 0, "Penundaan acak maksimal sebelum backup dimulai")
+			positional := parseFlags(fs, args[2:])
+			if len(positional) < 1 {
+				fmt.Println("Error: Nama database diperlukan")
+				printBackupHelp()
+				os.Exit(1)
+			}
+			backup.RunDatabase(positional[0], *format, *jitter)
+		} else if args[1] == "restore" {
+			fs := flag.NewFlagSet("backup dbbackup restore", flag.ExitOnError)
+			var options backup.RestoreOptions
+			fs.StringVar(&options.Snapshot, "snapshot", "", "ID snapshot yang dipulihkan (bawaan snapshot terbaru)")
+			fs.StringVar(&options.To, "to", "", "Tulis dump ke direktori staging, bukan ke database")
+			fs.BoolVar(&options.Safety, "safety-snapshot", false, "Buat dump keadaan saat ini sebelum menimpa")
+			positional := parseFlags(fs, args[2:])
+			if len(positional) < 1 {
+				fmt.Println("Error: Nama database diperlukan")
+				printBackupHelp()
+				os.Exit(1)
+			}
+			backup.RestoreDatabase(positional[0], options)
 		} else {
 			fmt.Printf("Error: Subperintah dbbackup tidak dikenal: %s\n", args[1])
 			printBackupHelp()
@@ -301,11 +782,125 @@ func handlePHPCommand(args []string) {
 	}
 }
 
+func handleImportCommand(args []string) {
+	if len(args) < 1 {
+		fmt.Println("Error: Sumber impor diperlukan")
+		printImportHelp()
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "Tampilkan hasil terjemahan tanpa membuat situs")
+	keepRoot := fs.Bool("keep-root", false, "Gunakan direktori root asli")
+	positional := parseFlags(fs, args[1:])
+	if len(positional) < 1 {
+		fmt.Println("Error: File atau direktori konfigurasi diperlukan")
+		printImportHelp()
+		os.Exit(1)
+	}
+	opts := importer.Options{DryRun: *dryRun, KeepRoot: *keepRoot}
+
+	source := args[0]
+	switch source {
+	case "nginx":
+		importer.Nginx(positional[0], opts)
+	case "apache":
+		importer.Apache(positional[0], opts)
+	default:
+		fmt.Printf("Error: Sumber impor tidak dikenal: %s\n", source)
+		printImportHelp()
+		os.Exit(1)
+	}
+}
+
 func handleInstallCommand(args []string) {
 	// Implementasi instalasi
 	utils.InstallDependencies()
 }
 
+// backupScheduleFlags mendaftarkan flag jadwal tugas backup
+func backupScheduleFlags(fs *flag.FlagSet) *backup.ScheduleOptions {
+	options := &backup.ScheduleOptions{}
+	fs.StringVar(&options.At, "at", "", "Waktu backup HH:MM")
+	fs.StringVar(&options.Cron, "cron", "", "Jadwal cron lima kolom, misalnya \"30 1 * * 1-5\"")
+	return options
+}
+
+func handleBackupRetention(args []string) {
+	if len(args) < 1 {
+		backup.ShowRetention()
+		return
+	}
+
+	switch args[0] {
+	case "show":
+		backup.ShowRetention()
+	case "set":
+		fs := flag.NewFlagSet("backup retention set", flag.ExitOnError)
+		daily := fs.Int("keep-daily", -1, "Jumlah snapshot harian yang disimpan")
+		weekly := fs.Int("keep-weekly", -1, "Jumlah snapshot mingguan yang disimpan")
+		monthly := fs.Int("keep-monthly", -1, "Jumlah snapshot bulanan yang disimpan")
+		positional := parseFlags(fs, args[1:])
+		if *daily < 0 && *weekly < 0 && *monthly < 0 {
+			fmt.Println("Error: Minimal satu dari --keep-daily, --keep-weekly atau --keep-monthly diperlukan")
+			printBackupHelp()
+			os.Exit(1)
+		}
+		domain := ""
+		if len(positional) > 0 {
+			domain = positional[0]
+		}
+		backup.SetRetention(domain, *daily, *weekly, *monthly)
+	case "unset":
+		if len(args) < 2 {
+			fmt.Println("Error: Domain diperlukan")
+			printBackupHelp()
+			os.Exit(1)
+		}
+		backup.UnsetRetention(args[1])
+	default:
+		fmt.Printf("Error: Subperintah retention tidak dikenal: %s\n", args[0])
+		printBackupHelp()
+		os.Exit(1)
+	}
+}
+
+// stringList adalah flag yang dapat diberikan lebih dari sekali
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// parseFlags mengurai flag yang boleh ditulis sebelum, di antara, atau sesudah argumen posisi
+func parseFlags(fs *flag.FlagSet, args []string) []string {
+	positional := []string{}
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// hasArg memeriksa apakah argumen tertentu diberikan
+func hasArg(args []string, arg string) bool {
+	for _, a := range args {
+		if a == arg || a == "-"+strings.TrimLeft(arg, "-") {
+			return true
+		}
+	}
+	return false
+}
+
 // Fungsi bantuan untuk mencetak dokumentasi
 func displayHelp() {
 	fmt.Println("Usage: webpanel [command] [options]")
@@ -317,6 +912,7 @@ func displayHelp() {
 	fmt.Println("  backup     Manage backup configurations")
 	fmt.Println("  db         Manage databases")
 	fmt.Println("  php        Manage PHP installations")
+	fmt.Println("  import     Import nginx or Apache virtual hosts")
 	fmt.Println("  help       Display help information")
 	fmt.Println("")
 	fmt.Println("Run 'webpanel help [command]' for more information on a command.")
@@ -328,31 +924,129 @@ func printSiteHelp() {
 	fmt.Println("  add <domain>      Menambahkan situs baru")
 	fmt.Println("  remove <domain>   Menghapus situs")
 	fmt.Println("  list              Menampilkan daftar situs")
+	fmt.Println("  info <domain>     Menampilkan konfigurasi situs")
+	fmt.Println("  tls set <domain> <mode> [argumen...]")
+	fmt.Println("                    Mengatur mode TLS situs, proxy atau pengalihan:")
+	fmt.Println("                      auto [email]          Sertifikat otomatis (ACME)")
+	fmt.Println("                      internal              Sertifikat dari CA internal Caddy")
+	fmt.Println("                      cert <cert> <key>     Sertifikat milik sendiri")
+	fmt.Println("                      dns <provider>        Tantangan DNS-01 (untuk wildcard)")
+	fmt.Println("  access allow <domain> <cidr> [--path /admin]")
+	fmt.Println("                    Hanya izinkan jaringan tertentu (situs atau proxy)")
+	fmt.Println("  access deny <domain> <cidr> [--path /admin]")
+	fmt.Println("                    Tolak jaringan tertentu")
+	fmt.Println("  access remove <domain> <cidr> [--path /admin]")
+	fmt.Println("                    Menghapus jaringan dari aturan akses")
+	fmt.Println("  access list <domain>")
+	fmt.Println("                    Menampilkan aturan akses")
+	fmt.Println("  export <domain> [-o bundle.tar.gz] [--db nama...]")
+	fmt.Println("                    Mengemas situs, konfigurasi dan database ke bundle")
+	fmt.Println("  import <bundle> [--domain domain-baru]")
+	fmt.Println("                    Membuat ulang situs dari bundle")
 }
 
 func printProxyHelp() {
 	fmt.Println("Penggunaan: webpanel proxy <subperintah> [argumen...]")
 	fmt.Println("\nSubperintah yang tersedia:")
-	fmt.Println("  add <domain> <target>   Menambahkan situs proxy baru")
+	fmt.Println("  add <domain> <target...> [opsi...]")
+	fmt.Println("                          Menambahkan situs proxy baru dengan satu atau lebih target")
+	fmt.Println("                          Target: host:port, http(s)://host:port, h2c://host:port, unix//path.sock,")
+	fmt.Println("                          /path.sock atau service:<nama>")
+	fmt.Println("      --socket <path>           Socket Unix sebagai target (boleh diulang, juga untuk update)")
+	fmt.Println("      --lb <policy>             round_robin, least_conn, ip_hash, first atau random")
+	fmt.Println("      --health-uri <uri>        URI pemeriksaan kesehatan upstream")
+	fmt.Println("      --health-interval <durasi> Interval pemeriksaan kesehatan, misalnya 10s")
+	fmt.Println("      --host-header <nilai>     Header Host ke upstream (upstream = host:port target)")
+	fmt.Println("      --header-up \"Nama nilai\"  Header ke upstream, \"-Nama\" untuk menghapus (boleh diulang)")
+	fmt.Println("      --header-down \"Nama nilai\" Header ke klien (boleh diulang)")
+	fmt.Println("      --dial-timeout, --read-timeout, --write-timeout <durasi>")
+	fmt.Println("      --no-buffering            Nonaktifkan buffering (streaming, SSE)")
+	fmt.Println("      --tls-skip-verify         Lewati verifikasi sertifikat upstream HTTPS")
+	fmt.Println("      --max-body-size <ukuran>  Ukuran maksimum body permintaan, misalnya 10MB")
+	fmt.Println("      --no-check                Lewati pemeriksaan koneksi ke target (juga untuk update, upstream add, route add)")
 	fmt.Println("  remove <domain>         Menghapus situs proxy")
-	fmt.Println("  list                    Menampilkan daftar situs proxy")
+	fmt.Println("  list                    Menampilkan daftar situs proxy beserta status koneksi target")
+	fmt.Println("  update <domain> [--target <target>...] [--<opsi> <nilai>...]")
+	fmt.Println("                          Mengubah target atau opsi proxy tanpa menghapusnya")
+	fmt.Println("  info <domain>           Menampilkan upstream dan opsi proxy")
+	fmt.Println("  set <domain> <opsi> <nilai>")
+	fmt.Println("                          Mengubah opsi proxy (nama opsi sama dengan flag add, \"off\" untuk mereset)")
+	fmt.Println("                          Koneksi WebSocket diteruskan otomatis oleh Caddy")
+	fmt.Println("                          Header X-Forwarded-* dari proxy lain dipercaya secara global lewat")
+	fmt.Println("                          'servers { trusted_proxies static <rentang> }' di /etc/caddy/Caddyfile")
+	fmt.Println("  upstream add <domain> <target>      Menambahkan upstream ke proxy")
+	fmt.Println("  upstream remove <domain> <target>   Menghapus upstream dari proxy")
+	fmt.Println("  route add <domain> <path> <target> [--strip-prefix]")
+	fmt.Println("                          Meneruskan path situs ke target lain")
+	fmt.Println("  route remove <domain> <path>        Menghapus rute path")
+	fmt.Println("  route list <domain>                 Menampilkan rute path situs")
+	fmt.Println("  service add <nama> <target> [--unit <unit>]")
+	fmt.Println("                          Mendaftarkan layanan yang dapat dipakai sebagai target service:<nama>")
+	fmt.Println("  service set <nama> <target>         Mengubah alamat layanan dan semua proxy yang memakainya")
+	fmt.Println("  service remove <nama>               Menghapus layanan yang tidak digunakan")
+	fmt.Println("  service list                        Menampilkan layanan beserta status unit dan koneksi")
 }
 
 func printModuleHelp() {
 	fmt.Println("Penggunaan: webpanel module <subperintah> [argumen...]")
 	fmt.Println("\nSubperintah yang tersedia:")
-	fmt.Println("  enable <module> <domain>    Mengaktifkan modul untuk domain")
+	fmt.Println("  enable <module> <domain> [--param nama=nilai...]")
+	fmt.Println("                              Mengaktifkan modul untuk domain, atau mengubah parameternya")
 	fmt.Println("  disable <module> <domain>   Menonaktifkan modul untuk domain")
-	fmt.Println("  list <domain>               Menampilkan modul yang diaktifkan untuk domain")
-	fmt.Println("  list-available              Menampilkan semua modul yang tersedia")
+	fmt.Println("  enable|disable <module> --all|--type <php|static|proxy|redirect>|--domains-from <file>")
+	fmt.Println("                              Mengaktifkan atau menonaktifkan modul untuk banyak situs")
+	fmt.Println("                              sekaligus dengan satu validasi dan satu reload")
+	fmt.Println("  list <domain>               Menampilkan modul yang diaktifkan beserta nilai parameternya")
+	fmt.Println("  list-available              Menampilkan modul yang tersedia beserta deskripsi, konflik dan parameter")
+	fmt.Println("  show <module>               Menampilkan isi, metadata dan situs yang memakai modul")
+	fmt.Println("  usage                       Menampilkan matriks modul yang dipakai setiap situs")
+	fmt.Println("  plugins                     Memeriksa plugin Caddy yang dibutuhkan modul")
+	fmt.Println("  build-caddy [--output <file>] [--with <paket>...] [--online]")
+	fmt.Println("                              Membangun Caddy dengan plugin yang dibutuhkan modul dari")
+	fmt.Println("                              cache modul Go lokal")
+	fmt.Println("  create <module> --from-file <file>")
+	fmt.Println("                              Membuat modul dari file berisi snippet (module)")
+	fmt.Println("  edit <module>               Menyunting modul dengan $EDITOR lalu mengujinya")
+	fmt.Println("  delete <module>             Menghapus modul yang tidak digunakan situs mana pun")
+	fmt.Println("  upgrade [--keep-modified]   Memperbarui modul bawaan; modul yang diubah secara lokal")
+	fmt.Println("                              ditampilkan perbedaannya dan hanya diganti jika disetujui")
+	fmt.Println("\nModul dapat diaktifkan untuk situs file server, PHP, proxy maupun pengalihan.")
+	fmt.Println("Nama modul adalah nama file di /etc/caddy/module.d tanpa ekstensi dan harus sama")
+	fmt.Println("dengan nama snippet; gunakan huruf kecil, angka, ., - dan _.")
 }
 
 func printBackupHelp() {
 	fmt.Println("Penggunaan: webpanel backup <subperintah> [argumen...]")
 	fmt.Println("\nSubperintah yang tersedia:")
-	fmt.Println("  enable <daily|weekly> <domain>    Mengaktifkan backup untuk domain")
+	fmt.Println("  enable <daily|weekly> <domain> [--at HH:MM|--cron <jadwal>] [--jitter <durasi>]")
+	fmt.Println("                                    Mengaktifkan atau menjadwalkan ulang backup domain")
 	fmt.Println("  disable <daily|weekly> <domain>   Menonaktifkan backup untuk domain")
-	fmt.Println("  dbbackup add <dbname>             Menambahkan backup database")
+	fmt.Println("  run <domain> [--type daily|weekly|manual] [--db <database>...] [--format zst|gz] [--jitter <durasi>]")
+	fmt.Println("                                    Membuat snapshot file situs dan database")
+	fmt.Println("  list [domain] [--json]            Menampilkan jadwal dan snapshot beserta ukuran dan umurnya")
+	fmt.Println("  status [--json]                   Menampilkan hasil terakhir setiap tugas backup")
+	fmt.Println("  prune [domain] [--dry-run]        Menghapus snapshot di luar kebijakan retensi")
+	fmt.Println("  retention [show]                  Menampilkan kebijakan retensi global dan per domain")
+	fmt.Println("  retention set [domain] [--keep-daily N] [--keep-weekly N] [--keep-monthly N]")
+	fmt.Println("                                    Mengatur retensi domain, atau global tanpa domain")
+	fmt.Println("  retention unset <domain>          Mengembalikan domain ke retensi global")
+	fmt.Println("  restore <domain> [--snapshot <id>] [--files-only|--db-only] [--to <path>] [--safety-snapshot]")
+	fmt.Println("                                    Memulihkan snapshot di tempat atau ke direktori staging")
+	fmt.Println("  dbbackup add <dbname> [--at HH:MM|--cron <jadwal>] [--jitter <durasi>]")
+	fmt.Println("                                    Menambahkan atau menjadwalkan ulang backup database")
+	fmt.Println("  dbbackup run <dbname> [--format zst|gz] [--jitter <durasi>]")
+	fmt.Println("                                    Membuat snapshot dump database")
+	fmt.Println("  dbbackup restore <dbname> [--snapshot <id>] [--to <path>] [--safety-snapshot]")
+	fmt.Println("                                    Memulihkan snapshot dump database")
+	fmt.Println("\nSnapshot disimpan di /backup/snapshots/<domain>/<waktu> berisi arsip terkompresi")
+	fmt.Println("dan manifest.json; backup terjadwal menjalankan 'webpanel backup run' dari cron.")
+	fmt.Println("Dump database disimpan di /backup/databases/<dbname>/<waktu> dan dipangkas dengan")
+	fmt.Println("retensi global; dump yang gagal atau tidak lengkap tidak pernah menggantikan snapshot.")
+	fmt.Println("Tanpa --at atau --cron, backup harian berjalan 02:00, mingguan Minggu 03:00 dan")
+	fmt.Println("database 04:00; --jitter menunda awal backup secara acak hingga durasi yang diberikan.")
+	fmt.Println("Setelah backup berhasil, snapshot harian dan mingguan di luar retensi dipangkas;")
+	fmt.Println("snapshot manual dan snapshot pengaman restore tidak pernah dipangkas otomatis.")
+	fmt.Println("Restore dengan --to menulis file ke <path>/files dan dump database ke <path>/databases.")
 }
 
 func printDatabaseHelp() {
@@ -378,3 +1072,10 @@ func printPHPModuleHelp() {
 	fmt.Println("  list <version>       Menampilkan modul PHP yang tersedia")
 	fmt.Println("  install <module>     Menginstal modul PHP")
 }
+
+func printImportHelp() {
+	fmt.Println("Penggunaan: webpanel import <nginx|apache> <file|direktori> [opsi...]")
+	fmt.Println("\nOpsi yang tersedia:")
+	fmt.Println("  --dry-run     Tampilkan hasil terjemahan tanpa membuat situs")
+	fmt.Println("  --keep-root   Gunakan direktori root asli alih-alih /apps/sites/<domain>")
+}
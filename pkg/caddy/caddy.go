@@ -3,6 +3,7 @@ package caddy
 import (
 	"fmt"
 	"os/exec"
+	"strings"
 )
 
 // Reload triggers a Caddy configuration reload
@@ -64,3 +65,31 @@ func ValidateConfig() error {
 	}
 	return nil
 }
+
+// ValidateFile validates a standalone Caddyfile, such as a temporary test config
+func ValidateFile(path string) error {
+	cmd := exec.Command("caddy", "validate", "--config", path, "--adapter", "caddyfile")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("invalid Caddy configuration: %v - %s", err, string(output))
+	}
+	return nil
+}
+
+// ListModules returns the IDs of the modules compiled into the Caddy binary
+func ListModules() ([]string, error) {
+	output, err := exec.Command("caddy", "list-modules").Output()
+	if err != nil {
+		return nil, fmt.Errorf("tidak dapat membaca daftar modul Caddy: %w", err)
+	}
+	modules := []string{}
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		// Lewati baris ringkasan seperti "Standard modules: 106"
+		if line == "" || strings.Contains(line, ":") {
+			continue
+		}
+		modules = append(modules, strings.Fields(line)[0])
+	}
+	return modules, nil
+}
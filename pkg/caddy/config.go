@@ -0,0 +1,86 @@
+package caddy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic writes a file through a temporary file in the same
+// directory so readers never see a partially written config
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	// Nama diawali titik agar tidak ikut diimpor oleh pola *.conf
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// ReplaceConfig atomically replaces a config file and validates the whole
+// Caddy configuration, restoring the previous content when it is invalid
+func ReplaceConfig(path string, data []byte) error {
+	return ReplaceConfigs(map[string][]byte{path: data})
+}
+
+// ReplaceConfigs atomically replaces several config files and validates the
+// whole Caddy configuration once, restoring every file when it is invalid
+func ReplaceConfigs(files map[string][]byte) error {
+	previous := map[string][]byte{}
+	for path := range files {
+		content, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		// nil menandai file baru yang dihapus saat pemulihan
+		previous[path] = content
+	}
+
+	written := []string{}
+	for path, data := range files {
+		if err := WriteFileAtomic(path, data, 0644); err != nil {
+			restoreConfigs(previous, written)
+			return fmt.Errorf("tidak dapat menulis file konfigurasi: %w", err)
+		}
+		written = append(written, path)
+	}
+
+	if err := ValidateConfig(); err != nil {
+		// Kembalikan konfigurasi lama
+		if restoreErr := restoreConfigs(previous, written); restoreErr != nil {
+			return fmt.Errorf("%v (konfigurasi lama tidak dapat dipulihkan: %v)", err, restoreErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// restoreConfigs mengembalikan isi lama file yang sudah ditulis
+func restoreConfigs(previous map[string][]byte, written []string) error {
+	var firstErr error
+	for _, path := range written {
+		var err error
+		if previous[path] == nil {
+			err = os.Remove(path)
+		} else {
+			err = WriteFileAtomic(path, previous[path], 0644)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
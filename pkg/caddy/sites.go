@@ -0,0 +1,75 @@
+package caddy
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SiteConfigDir is the directory of site configurations imported by the main Caddyfile
+const SiteConfigDir = "/etc/caddy/sites.d"
+
+// Site kinds, each stored under its own file name in SiteConfigDir
+const (
+	KindSite     = "site"     // <domain>.conf, file server atau PHP
+	KindProxy    = "proxy"    // proxy.<domain>.conf
+	KindRedirect = "redirect" // redirect.<domain>.conf
+)
+
+// SiteConfig is the configuration file of one domain
+type SiteConfig struct {
+	Domain string
+	Kind   string
+	Path   string
+}
+
+// ConfigFileName returns the file name of the configuration of a site kind
+func ConfigFileName(domain, kind string) string {
+	switch kind {
+	case KindProxy:
+		return "proxy." + domain + ".conf"
+	case KindRedirect:
+		return "redirect." + domain + ".conf"
+	default:
+		return domain + ".conf"
+	}
+}
+
+// FindSiteConfig resolves a domain to its configuration file regardless of
+// the site kind. The second result is false when the domain is not configured.
+func FindSiteConfig(domain string) (SiteConfig, bool) {
+	for _, kind := range []string{KindSite, KindProxy, KindRedirect} {
+		path := filepath.Join(SiteConfigDir, ConfigFileName(domain, kind))
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return SiteConfig{Domain: domain, Kind: kind, Path: path}, true
+		}
+	}
+	return SiteConfig{}, false
+}
+
+// SiteConfigs lists the configurations of every site kind sorted by domain
+func SiteConfigs() ([]SiteConfig, error) {
+	files, err := ioutil.ReadDir(SiteConfigDir)
+	if err != nil {
+		return nil, err
+	}
+	configs := []SiteConfig{}
+	for _, file := range files {
+		name := file.Name()
+		// Lewati file sementara yang diawali titik
+		if file.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, ".conf") {
+			continue
+		}
+		config := SiteConfig{Domain: strings.TrimSuffix(name, ".conf"), Kind: KindSite, Path: filepath.Join(SiteConfigDir, name)}
+		for _, kind := range []string{KindProxy, KindRedirect} {
+			if strings.HasPrefix(config.Domain, kind+".") {
+				config.Domain, config.Kind = strings.TrimPrefix(config.Domain, kind+"."), kind
+			}
+		}
+		configs = append(configs, config)
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Domain < configs[j].Domain })
+	return configs, nil
+}
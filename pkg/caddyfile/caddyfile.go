@@ -0,0 +1,339 @@
+// Package caddyfile parses Caddyfile configurations into a tree of lines that
+// can be edited and written back without losing comments, snippets or blocks.
+package caddyfile
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// Node is one line of a Caddyfile: a directive, site address or snippet with
+// an optional block, a comment line or a blank line
+type Node struct {
+	Tokens  []string // token mentah, tanda kutip dipertahankan
+	Comment string   // komentar di akhir baris atau baris komentar penuh, termasuk '#'
+	Open    bool     // baris membuka blok dengan '{'
+	Block   []*Node  // isi blok jika Open bernilai true
+	// EndComment adalah komentar setelah '}' penutup blok
+	EndComment string
+}
+
+// File is a parsed Caddyfile
+type File struct {
+	Nodes []*Node
+}
+
+// New creates a directive node, quoting arguments that contain whitespace
+func New(args ...string) *Node {
+	tokens := make([]string, len(args))
+	for i, arg := range args {
+		tokens[i] = Quote(arg)
+	}
+	return &Node{Tokens: tokens}
+}
+
+// Parse parses the contents of a Caddyfile
+func Parse(src string) (*File, error) {
+	lines, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	root := &Node{Open: true}
+	stack := []*Node{root}
+	for _, l := range lines {
+		parent := stack[len(stack)-1]
+
+		switch {
+		case len(l.tokens) == 0 && l.comment == "":
+			// Baris kosong berturut-turut digabung menjadi satu
+			if n := len(parent.Block); n > 0 && parent.Block[n-1].IsBlank() {
+				continue
+			}
+			parent.Block = append(parent.Block, &Node{})
+		case len(l.tokens) > 0 && l.tokens[0] == "}":
+			if len(l.tokens) > 1 {
+				return nil, fmt.Errorf("baris %d: token tidak diharapkan setelah '}'", l.number)
+			}
+			if len(stack) == 1 {
+				return nil, fmt.Errorf("baris %d: '}' tanpa pasangan '{'", l.number)
+			}
+			parent.EndComment = l.comment
+			stack = stack[:len(stack)-1]
+		case len(l.tokens) > 0 && l.tokens[len(l.tokens)-1] == "{":
+			node := &Node{Tokens: l.tokens[:len(l.tokens)-1], Comment: l.comment, Open: true, Block: []*Node{}}
+			parent.Block = append(parent.Block, node)
+			stack = append(stack, node)
+		default:
+			parent.Block = append(parent.Block, &Node{Tokens: l.tokens, Comment: l.comment})
+		}
+	}
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("blok '%s' tidak ditutup", strings.Join(stack[len(stack)-1].Tokens, " "))
+	}
+
+	return &File{Nodes: root.Block}, nil
+}
+
+// String formats the Caddyfile with tab indentation
+func (f *File) String() string {
+	var b strings.Builder
+	format(&b, trimBlank(f.Nodes), 0)
+	return b.String()
+}
+
+// Site returns the first site block of the file, skipping snippets and the
+// global options block
+func (f *File) Site() *Node {
+	for _, n := range f.Nodes {
+		if n.Open && len(n.Tokens) > 0 && !n.IsSnippet() {
+			return n
+		}
+	}
+	return nil
+}
+
+// Snippet returns the snippet with the given name
+func (f *File) Snippet(name string) *Node {
+	for _, n := range f.Nodes {
+		if n.IsSnippet() && n.SnippetName() == name {
+			return n
+		}
+	}
+	return nil
+}
+
+// IsBlank reports whether the node is an empty line
+func (n *Node) IsBlank() bool {
+	return len(n.Tokens) == 0 && n.Comment == "" && !n.Open
+}
+
+// IsSnippet reports whether the node defines a snippet such as (name)
+func (n *Node) IsSnippet() bool {
+	return n.Open && len(n.Tokens) == 1 && strings.HasPrefix(n.Tokens[0], "(") && strings.HasSuffix(n.Tokens[0], ")")
+}
+
+// SnippetName returns the name of a snippet without parentheses
+func (n *Node) SnippetName() string {
+	return strings.TrimSuffix(strings.TrimPrefix(n.Tokens[0], "("), ")")
+}
+
+// Name returns the directive name, or an empty string for comments and blank lines
+func (n *Node) Name() string {
+	if len(n.Tokens) == 0 {
+		return ""
+	}
+	return n.Tokens[0]
+}
+
+// Args returns the unquoted arguments of the directive
+func (n *Node) Args() []string {
+	args := []string{}
+	for i := 1; i < len(n.Tokens); i++ {
+		args = append(args, Unquote(n.Tokens[i]))
+	}
+	return args
+}
+
+// Arg returns the unquoted argument at index i, or an empty string
+func (n *Node) Arg(i int) string {
+	if i+1 >= len(n.Tokens) {
+		return ""
+	}
+	return Unquote(n.Tokens[i+1])
+}
+
+// Add appends nodes to the block of n, opening a block when needed
+func (n *Node) Add(children ...*Node) *Node {
+	n.Open = true
+	n.Block = append(n.Block, children...)
+	return n
+}
+
+// Find returns the first directive named name in the block of n
+func (n *Node) Find(name string) *Node {
+	for _, child := range n.Block {
+		if child.Name() == name {
+			return child
+		}
+	}
+	return nil
+}
+
+// FindAll returns every directive named name in the block of n
+func (n *Node) FindAll(name string) []*Node {
+	found := []*Node{}
+	for _, child := range n.Block {
+		if child.Name() == name {
+			found = append(found, child)
+		}
+	}
+	return found
+}
+
+// Remove removes the directives in the block of n that match and returns
+// how many were removed
+func (n *Node) Remove(match func(child *Node) bool) int {
+	kept := []*Node{}
+	removed := 0
+	for _, child := range n.Block {
+		if len(child.Tokens) > 0 && match(child) {
+			removed++
+			continue
+		}
+		kept = append(kept, child)
+	}
+	n.Block = kept
+	return removed
+}
+
+// Insert inserts nodes before the first directive named in before, or at the
+// end of the block
+func (n *Node) Insert(nodes []*Node, before ...string) {
+	index := len(n.Block)
+	for i, child := range n.Block {
+		if contains(before, child.Name()) {
+			index = i
+			break
+		}
+	}
+	block := make([]*Node, 0, len(n.Block)+len(nodes))
+	block = append(block, n.Block[:index]...)
+	block = append(block, nodes...)
+	n.Block = append(block, n.Block[index:]...)
+}
+
+// InsertAfter inserts nodes after the last directive named in after, or at
+// the start of the block
+func (n *Node) InsertAfter(nodes []*Node, after ...string) {
+	index := 0
+	for i, child := range n.Block {
+		if contains(after, child.Name()) {
+			index = i + 1
+		}
+	}
+	block := make([]*Node, 0, len(n.Block)+len(nodes))
+	block = append(block, n.Block[:index]...)
+	block = append(block, nodes...)
+	n.Block = append(block, n.Block[index:]...)
+}
+
+// Set replaces every directive named name with nodes, keeping the position of
+// the first one; new directives are placed at the start of the block
+func (n *Node) Set(name string, nodes ...*Node) {
+	index := -1
+	block := []*Node{}
+	for _, child := range n.Block {
+		if child.Name() == name {
+			if index < 0 {
+				index = len(block)
+			}
+			continue
+		}
+		block = append(block, child)
+	}
+	if index < 0 {
+		index = 0
+	}
+
+	n.Block = make([]*Node, 0, len(block)+len(nodes))
+	n.Block = append(n.Block, block[:index]...)
+	n.Block = append(n.Block, nodes...)
+	n.Block = append(n.Block, block[index:]...)
+}
+
+// Quote quotes a value that would otherwise be split into several tokens
+func Quote(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\n\"`") {
+		return value
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// Unquote removes the quotes around a token
+func Unquote(token string) string {
+	if len(token) >= 2 && token[0] == '`' && token[len(token)-1] == '`' {
+		return token[1 : len(token)-1]
+	}
+	if len(token) >= 2 && token[0] == '"' && token[len(token)-1] == '"' {
+		return strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(token[1 : len(token)-1])
+	}
+	return token
+}
+
+// format menulis node dengan indentasi tab sesuai kedalaman blok
+func format(b *strings.Builder, nodes []*Node, depth int) {
+	indent := strings.Repeat("\t", depth)
+	for _, n := range nodes {
+		if n.IsBlank() {
+			b.WriteString("\n")
+			continue
+		}
+
+		parts := append([]string{}, n.Tokens...)
+		if n.Open {
+			parts = append(parts, "{")
+		}
+		if n.Comment != "" {
+			parts = append(parts, n.Comment)
+		}
+		b.WriteString(indent + strings.Join(parts, " ") + "\n")
+
+		if n.Open {
+			format(b, trimBlank(n.Block), depth+1)
+			if n.EndComment != "" {
+				b.WriteString(indent + "} " + n.EndComment + "\n")
+			} else {
+				b.WriteString(indent + "}\n")
+			}
+		}
+	}
+}
+
+// trimBlank menghapus baris kosong di awal dan akhir blok
+func trimBlank(nodes []*Node) []*Node {
+	for len(nodes) > 0 && nodes[0].IsBlank() {
+		nodes = nodes[1:]
+	}
+	for len(nodes) > 0 && nodes[len(nodes)-1].IsBlank() {
+		nodes = nodes[:len(nodes)-1]
+	}
+	return nodes
+}
+
+// contains memeriksa apakah daftar berisi nilai
+func contains(items []string, value string) bool {
+	for _, item := range items {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Load reads and parses a Caddyfile from disk
+func Load(path string) (*File, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return file, nil
+}
+
+// LoadSite reads a site configuration and returns the file with its site block
+func LoadSite(path string) (*File, *Node, error) {
+	file, err := Load(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	site := file.Site()
+	if site == nil {
+		return nil, nil, fmt.Errorf("blok situs tidak ditemukan di %s", path)
+	}
+	return file, site, nil
+}
@@ -0,0 +1,292 @@
+package caddyfile
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{
+			name: "directives",
+			src:  "example.com {\n\troot * /apps/sites/example.com\n\tfile_server\n}\n",
+		},
+		{
+			name: "comments",
+			src: "# situs utama\n" +
+				"example.com { # alamat\n" +
+				"\t# root situs\n" +
+				"\troot * /apps/sites/example.com # root\n" +
+				"\tfile_server\n" +
+				"} # akhir situs\n",
+		},
+		{
+			name: "quoted tokens",
+			src: "example.com {\n" +
+				"\trespond \"Akses ditolak\" 403\n" +
+				"\theader X-Note \"a \\\"quoted\\\" value\"\n" +
+				"\trespond `raw # not a comment` 200\n" +
+				"\tpath /a#b\n" +
+				"}\n",
+		},
+		{
+			name: "nested blocks",
+			src: "example.com {\n" +
+				"\treverse_proxy localhost:3000 {\n" +
+				"\t\ttransport http {\n" +
+				"\t\t\tdial_timeout 5s\n" +
+				"\t\t}\n" +
+				"\t}\n" +
+				"\n" +
+				"\thandle_path /api/* {\n" +
+				"\t\treverse_proxy localhost:4000\n" +
+				"\t}\n" +
+				"}\n",
+		},
+		{
+			name: "snippets",
+			src: "(common) {\n" +
+				"\tencode gzip\n" +
+				"}\n" +
+				"\n" +
+				"(ratelimit) {\n" +
+				"\trate_limit {args[0]}\n" +
+				"}\n" +
+				"\n" +
+				"example.com {\n" +
+				"\timport common\n" +
+				"\timport ratelimit 10r/s\n" +
+				"}\n",
+		},
+		{
+			name: "heredoc",
+			src: "example.com {\n" +
+				"\trespond <<HTML\n" +
+				"\t\t<p>Maintenance</p>\n" +
+				"\t\tHTML 503\n" +
+				"}\n",
+		},
+		{
+			name: "global options",
+			src: "{\n" +
+				"\temail admin@example.com\n" +
+				"}\n" +
+				"\n" +
+				"example.com {\n" +
+				"\tfile_server\n" +
+				"}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := Parse(tt.src)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := file.String(); got != tt.src {
+				t.Errorf("String() =\n%s\nwant\n%s", got, tt.src)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "indentation",
+			src:  "example.com {\n    root * /srv\n  file_server\n}\n",
+			want: "example.com {\n\troot * /srv\n\tfile_server\n}\n",
+		},
+		{
+			name: "blank lines",
+			src:  "example.com {\n\n\troot * /srv\n\n\n\tfile_server\n\n}\n",
+			want: "example.com {\n\troot * /srv\n\n\tfile_server\n}\n",
+		},
+		{
+			name: "crlf",
+			src:  "example.com {\r\n\tfile_server\r\n}\r\n",
+			want: "example.com {\n\tfile_server\n}\n",
+		},
+		{
+			name: "backslash is part of the token",
+			src:  "example.com {\n\trespond a\\\n\tfile_server\n}\n",
+			want: "example.com {\n\trespond a\\\n\tfile_server\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := Parse(tt.src)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := file.String(); got != tt.want {
+				t.Errorf("String() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{name: "unclosed block", src: "example.com {\n\tfile_server\n"},
+		{name: "unmatched brace", src: "example.com\n}\n"},
+		{name: "token after brace", src: "example.com {\n} extra\n"},
+		{name: "unclosed quote", src: "example.com {\n\trespond \"text\n}\n"},
+		{name: "unclosed heredoc", src: "example.com {\n\trespond <<HTML\n\ttext\n}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.src); err == nil {
+				t.Errorf("Parse(%q) succeeded, want error", tt.src)
+			}
+		})
+	}
+}
+
+func TestEdit(t *testing.T) {
+	const src = "example.com {\n" +
+		"\troot * /srv\n" +
+		"\theader X-A a\n" +
+		"\theader X-B b\n" +
+		"\tfile_server\n" +
+		"}\n"
+
+	tests := []struct {
+		name string
+		edit func(site *Node)
+		want string
+	}{
+		{
+			name: "set replaces in place",
+			edit: func(site *Node) { site.Set("header", New("header", "X-C", "c d")) },
+			want: "example.com {\n\troot * /srv\n\theader X-C \"c d\"\n\tfile_server\n}\n",
+		},
+		{
+			name: "set adds at start",
+			edit: func(site *Node) { site.Set("encode", New("encode", "gzip")) },
+			want: "example.com {\n\tencode gzip\n\troot * /srv\n\theader X-A a\n\theader X-B b\n\tfile_server\n}\n",
+		},
+		{
+			name: "set without nodes removes",
+			edit: func(site *Node) { site.Set("header") },
+			want: "example.com {\n\troot * /srv\n\tfile_server\n}\n",
+		},
+		{
+			name: "insert before",
+			edit: func(site *Node) { site.Insert([]*Node{New("encode", "gzip")}, "file_server") },
+			want: "example.com {\n\troot * /srv\n\theader X-A a\n\theader X-B b\n\tencode gzip\n\tfile_server\n}\n",
+		},
+		{
+			name: "insert at end",
+			edit: func(site *Node) { site.Insert([]*Node{New("encode", "gzip")}, "missing") },
+			want: "example.com {\n\troot * /srv\n\theader X-A a\n\theader X-B b\n\tfile_server\n\tencode gzip\n}\n",
+		},
+		{
+			name: "insert after last",
+			edit: func(site *Node) { site.InsertAfter([]*Node{New("encode", "gzip")}, "header") },
+			want: "example.com {\n\troot * /srv\n\theader X-A a\n\theader X-B b\n\tencode gzip\n\tfile_server\n}\n",
+		},
+		{
+			name: "insert block",
+			edit: func(site *Node) {
+				site.Insert([]*Node{New("handle", "/api/*").Add(New("reverse_proxy", "localhost:3000"))}, "file_server")
+			},
+			want: "example.com {\n\troot * /srv\n\theader X-A a\n\theader X-B b\n" +
+				"\thandle /api/* {\n\t\treverse_proxy localhost:3000\n\t}\n\tfile_server\n}\n",
+		},
+		{
+			name: "remove",
+			edit: func(site *Node) {
+				if n := site.Remove(func(child *Node) bool { return child.Name() == "header" && child.Arg(0) == "X-A" }); n != 1 {
+					t.Errorf("Remove() = %d, want 1", n)
+				}
+			},
+			want: "example.com {\n\troot * /srv\n\theader X-B b\n\tfile_server\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := Parse(src)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			tt.edit(file.Site())
+			if got := file.String(); got != tt.want {
+				t.Errorf("String() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveKeepsComments(t *testing.T) {
+	file, err := Parse("example.com {\n\t# catatan\n\theader X-A a\n\tfile_server\n}\n")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	file.Site().Remove(func(child *Node) bool { return true })
+	want := "example.com {\n\t# catatan\n}\n"
+	if got := file.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	file, err := Parse("(common) {\n\tencode gzip\n}\n\nexample.com {\n\timport common\n\timport \"rate limit\" 10r/s\n}\n")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	site := file.Site()
+	if site == nil || site.Name() != "example.com" {
+		t.Fatalf("Site() = %v, want example.com", site)
+	}
+	if snippet := file.Snippet("common"); snippet == nil || !snippet.IsSnippet() {
+		t.Errorf("Snippet(common) not found")
+	}
+	imports := site.FindAll("import")
+	if len(imports) != 2 {
+		t.Fatalf("FindAll(import) = %d nodes, want 2", len(imports))
+	}
+	if got := imports[1].Arg(0); got != "rate limit" {
+		t.Errorf("Arg(0) = %q, want %q", got, "rate limit")
+	}
+	if got := strings.Join(imports[1].Args(), ","); got != "rate limit,10r/s" {
+		t.Errorf("Args() = %q", got)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "plain", want: "plain"},
+		{value: "", want: `""`},
+		{value: "two words", want: `"two words"`},
+		{value: `say "hi"`, want: `"say \"hi\""`},
+		{value: `back\slash x`, want: `"back\\slash x"`},
+	}
+
+	for _, tt := range tests {
+		if got := Quote(tt.value); got != tt.want {
+			t.Errorf("Quote(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+		if got := Unquote(Quote(tt.value)); got != tt.value {
+			t.Errorf("Unquote(Quote(%q)) = %q", tt.value, got)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package caddyfile
+
+import (
+	"fmt"
+	"strings"
+)
+
+// line adalah token satu baris logis beserta komentar di akhirnya
+type line struct {
+	number  int
+	tokens  []string
+	comment string
+}
+
+// tokenize memecah isi Caddyfile menjadi baris berisi token mentah. String
+// berkutip dan heredoc dapat melewati beberapa baris fisik.
+func tokenize(src string) ([]line, error) {
+	src = strings.Replace(src, "\r\n", "\n", -1)
+	lines := []line{}
+	current := line{number: 1}
+	number := 1
+	var token strings.Builder
+
+	endToken := func() {
+		if token.Len() > 0 {
+			current.tokens = append(current.tokens, token.String())
+			token.Reset()
+		}
+	}
+	endLine := func() {
+		endToken()
+		lines = append(lines, current)
+		current = line{number: number}
+	}
+
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == '\n':
+			number++
+			endLine()
+
+		case c == ' ' || c == '\t':
+			endToken()
+
+		case c == '#' && token.Len() == 0:
+			// Komentar berlaku sampai akhir baris
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src) - i
+			}
+			current.comment = strings.TrimRight(src[i:i+end], " \t")
+			i += end - 1
+
+		case (c == '"' || c == '`') && token.Len() == 0:
+			end, err := quotedEnd(src, i)
+			if err != nil {
+				return nil, fmt.Errorf("baris %d: %s", number, err)
+			}
+			token.WriteString(src[i : end+1])
+			number += strings.Count(src[i:end+1], "\n")
+			i = end
+
+		case c == '<' && token.Len() == 0 && strings.HasPrefix(src[i:], "<<"):
+			end, err := heredocEnd(src, i)
+			if err != nil {
+				return nil, fmt.Errorf("baris %d: %s", number, err)
+			}
+			token.WriteString(src[i:end])
+			number += strings.Count(src[i:end], "\n")
+			i = end - 1
+
+		default:
+			token.WriteByte(c)
+		}
+	}
+	endLine()
+
+	return lines, nil
+}
+
+// quotedEnd mencari posisi tanda kutip penutup untuk string yang dimulai di start
+func quotedEnd(src string, start int) (int, error) {
+	quote := src[start]
+	for i := start + 1; i < len(src); i++ {
+		if quote == '"' && src[i] == '\\' {
+			i++
+			continue
+		}
+		if src[i] == quote {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("string berkutip tidak ditutup")
+}
+
+// heredocEnd mencari akhir heredoc <<PENANDA yang dimulai di start, yaitu
+// setelah penanda pada baris penutup
+func heredocEnd(src string, start int) (int, error) {
+	lineEnd := strings.IndexByte(src[start:], '\n')
+	if lineEnd < 0 {
+		return 0, fmt.Errorf("heredoc tidak ditutup")
+	}
+	marker := strings.TrimSpace(src[start+2 : start+lineEnd])
+	if marker == "" || strings.ContainsAny(marker, " \t") {
+		return 0, fmt.Errorf("penanda heredoc tidak valid")
+	}
+
+	pos := start + lineEnd + 1
+	for pos <= len(src) {
+		next := strings.IndexByte(src[pos:], '\n')
+		end := len(src)
+		if next >= 0 {
+			end = pos + next
+		}
+		// Baris penutup boleh diikuti argumen lain, misalnya "HTML 200"
+		rest := strings.TrimLeft(src[pos:end], " \t")
+		if strings.HasPrefix(rest, marker) && (len(rest) == len(marker) || rest[len(marker)] == ' ' || rest[len(marker)] == '\t') {
+			return end - len(rest) + len(marker), nil
+		}
+		if next < 0 {
+			break
+		}
+		pos = end + 1
+	}
+	return 0, fmt.Errorf("heredoc %s tidak ditutup", marker)
+}
@@ -39,8 +39,8 @@ apt-get install -y curl wget gnupg2 ca-certificates lsb-release apt-transport-ht
 mkdir -p /etc/caddy/sites.d
 mkdir -p /etc/caddy/module.d
 mkdir -p /apps/sites
-mkdir -p /backup/daily
-mkdir -p /backup/weekly
+mkdir -p -m 0700 /backup/snapshots
+mkdir -p -m 0700 /backup/databases
 
 # install caddy
 apt-get install -y debian-keyring debian-archive-keyring apt-transport-https
@@ -103,89 +103,9 @@ import module.d/*
 import sites.d/*.conf
 EOF
 
-# Buat modul default
-mkdir -p /etc/caddy/module.d
-cat > /etc/caddy/module.d/spa << EOF
-(spa) {
-    @spa {
-        not path *.php
-        not path /api/*
-        not path *.js
-        not path *.css
-        not path *.png
-        not path *.jpg
-        not path *.jpeg
-        not path *.svg
-        not path *.gif
-        not path *.ico
-        not path *.woff
-        not path *.woff2
-        not path *.ttf
-        not path *.eot
-        file {
-            try_files {path} /index.html
-        }
-    }
-    rewrite @spa /index.html
-}
-EOF
-
-cat > /etc/caddy/module.d/security << EOF
-(security) {
-    header {
-        # Keamanan dasar
-        X-XSS-Protection "1; mode=block"
-        X-Content-Type-Options "nosniff"
-        X-Frame-Options "SAMEORIGIN"
-        Referrer-Policy "strict-origin-when-cross-origin"
-        
-        # Hapus header yang tidak perlu
-        -Server
-        -X-Powered-By
-    }
-}
-EOF
-
-cat > /etc/caddy/module.d/ratelimit << EOF
-(ratelimit) {
-    rate_limit {
-        zone dynamic {
-            key {remote_host}
-            events 100
-            window 10s
-        }
-    }
-}
-EOF
-
-cat > /etc/caddy/module.d/compression << EOF
-(compression) {
-    encode gzip zstd
-}
-EOF
-
-cat > /etc/caddy/module.d/cache-headers << EOF
-(cache-headers) {
-    @static {
-        path *.css *.js *.png *.jpg *.jpeg *.gif *.ico *.svg *.woff *.woff2 *.ttf *.eot
-    }
-    header @static Cache-Control "public, max-age=31536000"
-}
-EOF
-
-cat > /etc/caddy/module.d/local-access << EOF
-(local-access) {
-    @local {
-        remote_ip 127.0.0.1 192.168.0.0/16 10.0.0.0/8 172.16.0.0/12
-    }
-    @notLocal {
-        not remote_ip 127.0.0.1 192.168.0.0/16 10.0.0.0/8 172.16.0.0/12
-    }
-    handle @notLocal {
-        respond "Akses ditolak" 403
-    }
-}
-EOF
+# Pasang atau perbarui modul default; modul yang diubah secara lokal tidak ditimpa
+# dan import situs tanpa argumen dilengkapi dengan nilai default, aman dijalankan ulang
+/usr/local/bin/webpanel module upgrade --keep-modified
 
 # Bersihkan
 rm -rf /tmp/webpanel /tmp/webpanel.tar.gz